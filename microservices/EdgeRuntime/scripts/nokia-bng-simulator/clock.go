@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+// Clock abstracts wall time so scenario mode can drive the simulator off a
+// virtual clock that jumps straight to the next scheduled event instead of
+// actually waiting for it.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the default Clock for the ordinary random cycle loop: plain
+// wall time.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+// SimClock is the virtual clock scenario mode runs on. Its Now() only moves
+// when RunScenario calls Advance, so every timestamp a scenario run records
+// (session StartTime, span time, etc.) reflects virtual scenario time, not
+// however long the run actually took on the host.
+type SimClock struct {
+	now time.Time // only ever touched from the single-goroutine scenario runner
+}
+
+// NewSimClock returns a SimClock starting at start.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+func (c *SimClock) Now() time.Time { return c.now }
+
+// Advance moves the virtual clock forward to t. It never moves backward.
+func (c *SimClock) Advance(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+}
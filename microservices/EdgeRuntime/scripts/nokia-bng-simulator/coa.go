@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2866"
+	"layeh.com/radius/rfc3576"
+)
+
+// coaListenPort is the well-known CoA/Disconnect port (RFC 5176 §3.3) that a
+// real Nokia 7750 SR listens on.
+const coaListenPort = 3799
+
+// Nokia VSA layout used to carry an operator-selected TrafficProfile name in
+// a CoA-Request, in lieu of Filter-Id.
+const (
+	nokiaVendorID          = 94
+	nokiaTrafficProfileSub = 252
+)
+
+// CoAServer makes the simulator behave like a real NAS from the operator's
+// side: it binds UDP/3799 and answers RFC 5176 CoA-Request / Disconnect-
+// Request packets against the sessions tracked in sim.sessions.
+type CoAServer struct {
+	sim *Simulator
+	srv *radius.PacketServer
+}
+
+// newCoAServer builds a CoAServer bound to sim.coaAddr, validating every
+// inbound packet against sim.radiusSecret.
+func newCoAServer(sim *Simulator) *CoAServer {
+	cs := &CoAServer{sim: sim}
+	cs.srv = &radius.PacketServer{
+		Addr:         sim.coaAddr,
+		Handler:      radius.HandlerFunc(cs.serveRADIUS),
+		SecretSource: radius.StaticSecretSource(sim.radiusSecret),
+	}
+	return cs
+}
+
+// ListenAndServe blocks serving CoA/Disconnect requests until the listener
+// fails; callers run it in its own goroutine alongside the cycle loop.
+func (cs *CoAServer) ListenAndServe() error {
+	return cs.srv.ListenAndServe()
+}
+
+func (cs *CoAServer) serveRADIUS(w radius.ResponseWriter, r *radius.Request) {
+	switch r.Code {
+	case radius.CodeDisconnectRequest:
+		cs.handleDisconnect(w, r)
+	case radius.CodeCoARequest:
+		cs.handleCoA(w, r)
+	}
+}
+
+// findSession resolves the session a CoA/Disconnect-Request targets, trying
+// Acct-Session-Id first and falling back to User-Name, under the same lock
+// doConnect/doDisconnect use.
+func (cs *CoAServer) findSession(r *radius.Request) *Session {
+	sessionID := rfc2866.AcctSessionID_GetString(r.Packet)
+	username := rfc2865.UserName_GetString(r.Packet)
+
+	cs.sim.mu.Lock()
+	defer cs.sim.mu.Unlock()
+
+	if sessionID != "" {
+		for _, sess := range cs.sim.sessions {
+			if sess.SessionID == sessionID {
+				return sess
+			}
+		}
+	}
+	if username != "" {
+		if sess, ok := cs.sim.sessions[username]; ok {
+			return sess
+		}
+	}
+	return nil
+}
+
+func (cs *CoAServer) handleDisconnect(w radius.ResponseWriter, r *radius.Request) {
+	sess := cs.findSession(r)
+	if sess == nil {
+		metrics.CoARequests.WithLabelValues("disconnect", "nak").Inc()
+		cs.nak(w, r, radius.CodeDisconnectNAK, rfc3576.ErrorCause_Value_SessionContextNotFound)
+		return
+	}
+
+	cs.sim.doDisconnect(sess, rfc2866.AcctTerminateCause_Value_AdminReset)
+	metrics.CoARequests.WithLabelValues("disconnect", "ack").Inc()
+	cs.sim.logEvent("COA", fmt.Sprintf("Disconnect-Request honored for %s", sess.Username))
+	w.Write(radius.New(radius.CodeDisconnectACK, r.Secret))
+}
+
+func (cs *CoAServer) handleCoA(w radius.ResponseWriter, r *radius.Request) {
+	sess := cs.findSession(r)
+	if sess == nil {
+		metrics.CoARequests.WithLabelValues("coa", "nak").Inc()
+		cs.nak(w, r, radius.CodeCoANAK, rfc3576.ErrorCause_Value_SessionContextNotFound)
+		return
+	}
+
+	name := rfc2865.FilterId_GetString(r.Packet)
+	if name == "" {
+		name = cs.vendorProfileName(r.Packet)
+	}
+	prof := profileByName(name)
+	if prof == nil {
+		metrics.CoARequests.WithLabelValues("coa", "nak").Inc()
+		cs.nak(w, r, radius.CodeCoANAK, rfc3576.ErrorCause_Value_InvalidAttributeValue)
+		return
+	}
+
+	cs.sim.mu.Lock()
+	sess.Profile = prof
+	cs.sim.mu.Unlock()
+
+	metrics.CoARequests.WithLabelValues("coa", "ack").Inc()
+	cs.sim.logEvent("COA", fmt.Sprintf("CoA-Request: %s -> profile=%s", sess.Username, prof.Name))
+	w.Write(radius.New(radius.CodeCoAACK, r.Secret))
+}
+
+// vendorProfileName extracts a Nokia-VSA-encoded TrafficProfile name, the
+// fallback an operator uses when their policy server can't send Filter-Id.
+func (cs *CoAServer) vendorProfileName(pkt *radius.Packet) string {
+	raw, ok := pkt.Lookup(radius.Type(26)) // Vendor-Specific
+	if !ok {
+		return ""
+	}
+	vendorID, vsa, err := radius.VendorSpecific(raw)
+	if err != nil || vendorID != nokiaVendorID {
+		return ""
+	}
+	for len(vsa) >= 2 {
+		subType, subLen := vsa[0], int(vsa[1])
+		if subLen < 2 || subLen > len(vsa) {
+			break
+		}
+		if subType == nokiaTrafficProfileSub {
+			return string(vsa[2:subLen])
+		}
+		vsa = vsa[subLen:]
+	}
+	return ""
+}
+
+func (cs *CoAServer) nak(w radius.ResponseWriter, r *radius.Request, code radius.Code, cause rfc3576.ErrorCause) {
+	resp := radius.New(code, r.Secret)
+	rfc3576.ErrorCause_Set(resp, cause)
+	w.Write(resp)
+}
+
+// profileByName looks up a TrafficProfile by its Name field, as set by a
+// Filter-Id or Nokia VSA on an inbound CoA-Request.
+func profileByName(name string) *TrafficProfile {
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
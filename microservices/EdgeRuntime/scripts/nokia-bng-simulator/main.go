@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"math/rand"
 	"net"
@@ -15,7 +14,7 @@ import (
 	"syscall"
 	"time"
 
-	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
 	"layeh.com/radius"
 	"layeh.com/radius/rfc2865"
 	"layeh.com/radius/rfc2866"
@@ -105,6 +104,17 @@ type Simulator struct {
 	disconnectPct  int
 	burstSize      int
 	headless       bool
+	metricsAddr    string
+	metricsEnabled bool
+	coaAddr        string
+	controlAddr    string
+	transport      Transport
+	subscriberSrc  SubscriberSource
+	clock          Clock
+	rateLimits     RateLimits
+	authBucket     *tokenBucket
+	acctBucket     *tokenBucket
+	sinks          []StatsSink
 
 	subscribers []Subscriber
 	sessions    map[string]*Session
@@ -143,6 +153,11 @@ var profiles = []TrafficProfile{
 	{Name: "iptv", DLMin: 300_000_000, DLMax: 2_000_000_000, ULMin: 1_000_000, ULMax: 10_000_000},
 }
 
+// nasPortTypeLabel is the Prometheus label value matching the NAS-Port-Type
+// (rfc2865.NASPortType_Value_Ethernet) every simulated session's accounting
+// packets carry; sendAuth's Access-Request itself sets no NAS-Port-Type.
+const nasPortTypeLabel = "Ethernet"
+
 var terminateCauses = []rfc2866.AcctTerminateCause{
 	rfc2866.AcctTerminateCause_Value_UserRequest,
 	rfc2866.AcctTerminateCause_Value_LostCarrier,
@@ -170,7 +185,7 @@ func envInt(key string, def int) int {
 
 func NewSimulator() *Simulator {
 	cycleMs := envInt("CYCLE_MS", 500)
-	return &Simulator{
+	s := &Simulator{
 		radiusAuthAddr: envOr("RADIUS_HOST", "freeradius") + ":" + envOr("RADIUS_AUTH_PORT", "1812"),
 		radiusAcctAddr: envOr("RADIUS_HOST", "freeradius") + ":" + envOr("RADIUS_ACCT_PORT", "1813"),
 		radiusSecret:   []byte(envOr("RADIUS_SECRET", "testing123")),
@@ -182,9 +197,39 @@ func NewSimulator() *Simulator {
 		disconnectPct:  envInt("DISCONNECT_CHANCE", 5),
 		burstSize:      envInt("BURST_SIZE", 20),
 		headless:       envOr("HEADLESS", "false") == "true",
+		metricsAddr:    envOr("PROMETHEUS_LISTEN", envOr("METRICS_ADDR", ":9110")),
+		metricsEnabled: envOr("METRICS_ENABLED", "true") == "true",
+		coaAddr:        envOr("COA_ADDR", fmt.Sprintf(":%d", coaListenPort)),
+		controlAddr:    envOr("CONTROL_ADDR", ":9120"),
 		sessions:       make(map[string]*Session),
 		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+		clock:          RealClock{},
+		rateLimits: RateLimits{
+			AuthPPS:         int64(envInt("AUTH_PPS", 0)),
+			AcctPPS:         int64(envInt("ACCT_PPS", 0)),
+			BurstPackets:    defaultRateBurst,
+			LatencyMeanMs:   int64(envInt("NET_LATENCY_MS", 0)),
+			LatencyJitterMs: int64(envInt("NET_JITTER_MS", 0)),
+			PacketLossPct:   int64(envInt("NET_LOSS_PCT", 0)),
+		},
+	}
+	s.authBucket = newTokenBucket(&s.rateLimits.AuthPPS, &s.rateLimits.BurstPackets)
+	s.acctBucket = newTokenBucket(&s.rateLimits.AcctPPS, &s.rateLimits.BurstPackets)
+
+	transport, err := newTransport()
+	if err != nil {
+		fmt.Printf("[warn] %v — falling back to plain UDP\n", err)
+		transport = udpTransport{}
 	}
+	s.transport = transport
+	s.subscriberSrc = newSubscriberSource(s.pgConnStr, s.maxSubs)
+
+	if s.metricsEnabled {
+		fmt.Printf("[init] Serving Prometheus metrics on %s/metrics\n", s.metricsAddr)
+		serveMetrics(s.metricsAddr)
+	}
+
+	return s
 }
 
 // ---------------------------------------------------------------------------
@@ -192,39 +237,199 @@ func NewSimulator() *Simulator {
 // ---------------------------------------------------------------------------
 
 func (s *Simulator) loadSubscribers() error {
-	fmt.Println("[init] Loading subscribers from PostgreSQL...")
-	db, err := sql.Open("postgres", s.pgConnStr)
+	subs, err := s.subscriberSrc.Load(context.Background())
 	if err != nil {
-		return fmt.Errorf("pg connect: %w", err)
+		return err
 	}
-	defer db.Close()
+	s.subscribers = subs
+	metrics.SubscriberPoolSize.Set(float64(len(subs)))
+	return nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// applySubscriberEvent applies one provisioning change from subscriberSrc's
+// Watch channel: an Upsert adds the subscriber to the pool or replaces its
+// existing entry, a Delete removes it and force-disconnects any active
+// session with an operator-style Acct-Stop, the same outcome a real RFC 5176
+// Disconnect-Message produces via the CoA listener.
+func (s *Simulator) applySubscriberEvent(ev SubscriberEvent) {
+	switch ev.Type {
+	case SubscriberUpsert:
+		s.mu.Lock()
+		replaced := false
+		for i := range s.subscribers {
+			if s.subscribers[i].Username == ev.Sub.Username {
+				s.subscribers[i] = ev.Sub
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.subscribers = append(s.subscribers, ev.Sub)
+		}
+		poolSize := len(s.subscribers)
+		s.mu.Unlock()
+		metrics.SubscriberPoolSize.Set(float64(poolSize))
+		s.logEvent("PROVISION", fmt.Sprintf("upsert %s", ev.Sub.Username))
 
-	rows, err := db.QueryContext(ctx,
-		`SELECT "Username", "Password" FROM "RadiusUsers"
-		 WHERE "Enabled"=true AND "IsDeleted"=false AND "Password" IS NOT NULL
-		 ORDER BY random() LIMIT $1`, s.maxSubs)
-	if err != nil {
-		return fmt.Errorf("pg query: %w", err)
+	case SubscriberDelete:
+		s.mu.Lock()
+		for i := range s.subscribers {
+			if s.subscribers[i].Username == ev.Username {
+				s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+				break
+			}
+		}
+		poolSize := len(s.subscribers)
+		sess, active := s.sessions[ev.Username]
+		s.mu.Unlock()
+		metrics.SubscriberPoolSize.Set(float64(poolSize))
+		s.logEvent("PROVISION", fmt.Sprintf("delete %s", ev.Username))
+		if active {
+			s.doDisconnect(sess, rfc2866.AcctTerminateCause_Value_AdminReset)
+		}
 	}
-	defer rows.Close()
+}
 
-	for rows.Next() {
-		var sub Subscriber
-		if err := rows.Scan(&sub.Username, &sub.Password); err != nil {
-			continue
+// subscriberByUsername returns a copy of the pooled subscriber matching
+// username, or nil if the pool has none — used by the control API to force-
+// connect a specific subscriber on demand.
+func (s *Simulator) subscriberByUsername(username string) *Subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.subscribers {
+		if s.subscribers[i].Username == username {
+			sub := s.subscribers[i]
+			return &sub
 		}
-		s.subscribers = append(s.subscribers, sub)
 	}
-	if len(s.subscribers) == 0 {
-		return fmt.Errorf("no subscribers found")
-	}
-	fmt.Printf("[init] Loaded %d subscribers from CDC pool\n", len(s.subscribers))
 	return nil
 }
 
+// activeSession returns the session tracked for username, if any, under the
+// same lock doConnect/doDisconnect use.
+func (s *Simulator) activeSession(username string) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[username]
+	return sess, ok
+}
+
+// CycleDuration returns the current cycle period, which the control API may
+// change live.
+func (s *Simulator) CycleDuration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cycleDuration
+}
+
+// SimConfig is the subset of Simulator's settings the control API can read
+// and change live, without a restart.
+type SimConfig struct {
+	CycleMs          int   `json:"cycle_ms"`
+	ConnectsPerCycle int   `json:"connects_per_cycle"`
+	BurstSize        int   `json:"burst_size"`
+	DisconnectChance int   `json:"disconnect_chance"`
+	MaxSubscribers   int   `json:"max_subscribers"`
+	AuthPPS          int64 `json:"auth_pps"`
+	AcctPPS          int64 `json:"acct_pps"`
+	LatencyMeanMs    int64 `json:"latency_mean_ms"`
+	LatencyJitterMs  int64 `json:"latency_jitter_ms"`
+	PacketLossPct    int64 `json:"packet_loss_pct"`
+}
+
+// SimConfigPatch mirrors SimConfig but with pointer fields, so
+// ApplyConfigPatch can tell an omitted field apart from an explicit zero.
+type SimConfigPatch struct {
+	CycleMs          *int   `json:"cycle_ms,omitempty"`
+	ConnectsPerCycle *int   `json:"connects_per_cycle,omitempty"`
+	BurstSize        *int   `json:"burst_size,omitempty"`
+	DisconnectChance *int   `json:"disconnect_chance,omitempty"`
+	MaxSubscribers   *int   `json:"max_subscribers,omitempty"`
+	AuthPPS          *int64 `json:"auth_pps,omitempty"`
+	AcctPPS          *int64 `json:"acct_pps,omitempty"`
+	LatencyMeanMs    *int64 `json:"latency_mean_ms,omitempty"`
+	LatencyJitterMs  *int64 `json:"latency_jitter_ms,omitempty"`
+	PacketLossPct    *int64 `json:"packet_loss_pct,omitempty"`
+}
+
+// Config returns a snapshot of the live-tunable settings.
+func (s *Simulator) Config() SimConfig {
+	s.mu.Lock()
+	cfg := SimConfig{
+		CycleMs:          int(s.cycleDuration.Milliseconds()),
+		ConnectsPerCycle: s.connectsPerCyc,
+		BurstSize:        s.burstSize,
+		DisconnectChance: s.disconnectPct,
+		MaxSubscribers:   s.maxSubs,
+	}
+	s.mu.Unlock()
+
+	cfg.AuthPPS = atomic.LoadInt64(&s.rateLimits.AuthPPS)
+	cfg.AcctPPS = atomic.LoadInt64(&s.rateLimits.AcctPPS)
+	cfg.LatencyMeanMs = atomic.LoadInt64(&s.rateLimits.LatencyMeanMs)
+	cfg.LatencyJitterMs = atomic.LoadInt64(&s.rateLimits.LatencyJitterMs)
+	cfg.PacketLossPct = atomic.LoadInt64(&s.rateLimits.PacketLossPct)
+	return cfg
+}
+
+// ApplyConfigPatch live-updates only the fields patch sets. Callers whose
+// cycle loop uses a *time.Ticker should re-Reset it to CycleDuration()
+// afterwards in case patch changed it.
+func (s *Simulator) ApplyConfigPatch(patch SimConfigPatch) {
+	s.mu.Lock()
+	if patch.CycleMs != nil {
+		s.cycleDuration = time.Duration(*patch.CycleMs) * time.Millisecond
+	}
+	if patch.ConnectsPerCycle != nil {
+		s.connectsPerCyc = *patch.ConnectsPerCycle
+	}
+	if patch.BurstSize != nil {
+		s.burstSize = *patch.BurstSize
+	}
+	if patch.DisconnectChance != nil {
+		s.disconnectPct = *patch.DisconnectChance
+	}
+	if patch.MaxSubscribers != nil {
+		s.maxSubs = *patch.MaxSubscribers
+	}
+	s.mu.Unlock()
+
+	// AuthPPS/AcctPPS/latency/loss are read with atomic.Load from the
+	// exchange hot path (RateLimits' doc comment), so they're set the same
+	// way here rather than under s.mu.
+	if patch.AuthPPS != nil {
+		atomic.StoreInt64(&s.rateLimits.AuthPPS, *patch.AuthPPS)
+	}
+	if patch.AcctPPS != nil {
+		atomic.StoreInt64(&s.rateLimits.AcctPPS, *patch.AcctPPS)
+	}
+	if patch.LatencyMeanMs != nil {
+		atomic.StoreInt64(&s.rateLimits.LatencyMeanMs, *patch.LatencyMeanMs)
+	}
+	if patch.LatencyJitterMs != nil {
+		atomic.StoreInt64(&s.rateLimits.LatencyJitterMs, *patch.LatencyJitterMs)
+	}
+	if patch.PacketLossPct != nil {
+		atomic.StoreInt64(&s.rateLimits.PacketLossPct, *patch.PacketLossPct)
+	}
+}
+
+// StatsSnapshot atomically reads every counter in s.stats.
+func (s *Simulator) StatsSnapshot() Stats {
+	return Stats{
+		AuthOK:   atomic.LoadInt64(&s.stats.AuthOK),
+		Rejects:  atomic.LoadInt64(&s.stats.Rejects),
+		Starts:   atomic.LoadInt64(&s.stats.Starts),
+		Interims: atomic.LoadInt64(&s.stats.Interims),
+		Stops:    atomic.LoadInt64(&s.stats.Stops),
+		Errors:   atomic.LoadInt64(&s.stats.Errors),
+		Cycles:   atomic.LoadInt64(&s.stats.Cycles),
+		TotalDL:  atomic.LoadInt64(&s.stats.TotalDL),
+		TotalUL:  atomic.LoadInt64(&s.stats.TotalUL),
+		PktsSent: atomic.LoadInt64(&s.stats.PktsSent),
+	}
+}
+
 // ---------------------------------------------------------------------------
 // ID / Address Generators
 // ---------------------------------------------------------------------------
@@ -263,29 +468,77 @@ func (s *Simulator) genNASPortID(bng *BNG) (uint32, string) {
 // RADIUS Protocol
 // ---------------------------------------------------------------------------
 
-func (s *Simulator) sendAuth(username, password string) error {
+func (s *Simulator) sendAuth(ctx context.Context, bng *BNG, username, password string) error {
+	ctx, span := tracer.Start(ctx, "sendAuth")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("nas.ip", bng.IP.String()),
+		attribute.String("nas.name", bng.Name),
+		attribute.String("subscriber.username", username),
+	)
+
 	pkt := radius.New(radius.CodeAccessRequest, s.radiusSecret)
 	rfc2865.UserName_SetString(pkt, username)
 	rfc2865.UserPassword_SetString(pkt, password)
-	rfc2865.NASIPAddress_Set(pkt, bngs[0].IP)
+	rfc2865.NASIPAddress_Set(pkt, bng.IP)
 	rfc2865.ServiceType_Set(pkt, rfc2865.ServiceType_Value_FramedUser)
 	rfc2865.FramedProtocol_Set(pkt, rfc2865.FramedProtocol_Value_PPP)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	atomic.AddInt64(&s.stats.PktsSent, 1)
-	resp, err := radius.Exchange(ctx, pkt, s.radiusAuthAddr)
+	resp, err := s.doExchange(ctx, "auth", s.radiusAuthAddr, pkt)
 	if err != nil {
-		return fmt.Errorf("auth exchange: %w", err)
+		span.RecordError(err)
+		return err
 	}
 	if resp.Code != radius.CodeAccessAccept {
-		return fmt.Errorf("rejected (code=%d)", resp.Code)
+		err := fmt.Errorf("rejected (code=%d)", resp.Code)
+		span.RecordError(err)
+		return err
 	}
 	return nil
 }
 
-func (s *Simulator) sendAcctStart(sess *Session) error {
+// doExchange is the common span+metrics wrapper around a single RADIUS
+// round trip, shared by sendAuth and exchangeAcct; label is "auth" or
+// "acct" for metric/attribute purposes.
+func (s *Simulator) doExchange(ctx context.Context, label, addr string, pkt *radius.Packet) (*radius.Packet, error) {
+	ctx, span := tracer.Start(ctx, "radius.Exchange")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("radius.code", int(pkt.Code)),
+		attribute.Int("radius.identifier", int(pkt.Identifier)),
+	)
+
+	bucket := s.authBucket
+	if label == "acct" {
+		bucket = s.acctBucket
+	}
+	if err := bucket.Take(ctx); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%s rate limit: %w", label, err)
+	}
+
+	atomic.AddInt64(&s.stats.PktsSent, 1)
+	metrics.PacketsSent.Inc()
+	start := time.Now()
+	resp, err := s.transport.Exchange(ctx, addr, pkt)
+	resp, err = s.applyNetworkConditions(ctx, resp, err)
+	metrics.RTT.WithLabelValues(label).Observe(time.Since(start).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		metrics.Errors.WithLabelValues(label).Inc()
+		return nil, fmt.Errorf("%s exchange: %w", label, err)
+	}
+	return resp, nil
+}
+
+func (s *Simulator) sendAcctStart(ctx context.Context, sess *Session) error {
+	ctx, span := tracer.Start(ctx, "sendAcctStart")
+	defer span.End()
+	span.SetAttributes(acctSpanAttributes(sess)...)
+
 	pkt := radius.New(radius.CodeAccountingRequest, s.radiusSecret)
 	rfc2866.AcctStatusType_Set(pkt, rfc2866.AcctStatusType_Value_Start)
 	rfc2866.AcctSessionID_SetString(pkt, sess.SessionID)
@@ -301,10 +554,14 @@ func (s *Simulator) sendAcctStart(sess *Session) error {
 	rfc2869.AcctInterimInterval_Set(pkt, rfc2869.AcctInterimInterval(s.interimSecs))
 	rfc2865.NASIdentifier_SetString(pkt, sess.BNG.Name)
 	rfc2866.AcctAuthentic_Set(pkt, rfc2866.AcctAuthentic_Value_RADIUS)
-	return s.exchangeAcct(pkt)
+	return s.exchangeAcct(ctx, pkt)
 }
 
-func (s *Simulator) sendAcctInterim(sess *Session) error {
+func (s *Simulator) sendAcctInterim(ctx context.Context, sess *Session) error {
+	ctx, span := tracer.Start(ctx, "sendAcctInterim")
+	defer span.End()
+	span.SetAttributes(acctSpanAttributes(sess)...)
+
 	pkt := radius.New(radius.CodeAccountingRequest, s.radiusSecret)
 	const giga uint64 = 4294967296
 
@@ -353,10 +610,15 @@ func (s *Simulator) sendAcctInterim(sess *Session) error {
 	rfc2869.AcctInputGigawords_Set(pkt, rfc2869.AcctInputGigawords(dlGiga))
 	rfc2869.AcctOutputGigawords_Set(pkt, rfc2869.AcctOutputGigawords(ulGiga))
 	rfc2866.AcctAuthentic_Set(pkt, rfc2866.AcctAuthentic_Value_RADIUS)
-	return s.exchangeAcct(pkt)
+	return s.exchangeAcct(ctx, pkt)
 }
 
-func (s *Simulator) sendAcctStop(sess *Session, cause rfc2866.AcctTerminateCause) error {
+func (s *Simulator) sendAcctStop(ctx context.Context, sess *Session, cause rfc2866.AcctTerminateCause) error {
+	ctx, span := tracer.Start(ctx, "sendAcctStop")
+	defer span.End()
+	span.SetAttributes(acctSpanAttributes(sess)...)
+	span.SetAttributes(attribute.String("radius.terminate_cause", cause.String()))
+
 	pkt := radius.New(radius.CodeAccountingRequest, s.radiusSecret)
 	const giga uint64 = 4294967296
 	dlGiga := uint32(uint64(sess.TotalDL) / giga)
@@ -377,21 +639,59 @@ func (s *Simulator) sendAcctStop(sess *Session, cause rfc2866.AcctTerminateCause
 	rfc2869.AcctInputGigawords_Set(pkt, rfc2869.AcctInputGigawords(dlGiga))
 	rfc2869.AcctOutputGigawords_Set(pkt, rfc2869.AcctOutputGigawords(ulGiga))
 	rfc2866.AcctAuthentic_Set(pkt, rfc2866.AcctAuthentic_Value_RADIUS)
-	return s.exchangeAcct(pkt)
+	return s.exchangeAcct(ctx, pkt)
 }
 
-func (s *Simulator) exchangeAcct(pkt *radius.Packet) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-	atomic.AddInt64(&s.stats.PktsSent, 1)
-	resp, err := radius.Exchange(ctx, pkt, s.radiusAcctAddr)
+// InjectMalformedAccounting sends a deliberately incomplete Accounting-
+// Request for sess — no Acct-Status-Type, no Acct-Session-Id — so a test
+// harness can exercise FreeRADIUS's handling of malformed accounting
+// traffic via the control API.
+func (s *Simulator) InjectMalformedAccounting(ctx context.Context, sess *Session) error {
+	pkt := radius.New(radius.CodeAccountingRequest, s.radiusSecret)
+	rfc2865.UserName_SetString(pkt, sess.Username)
+	rfc2865.NASIPAddress_Set(pkt, sess.BNG.IP)
+	return s.exchangeAcct(ctx, pkt)
+}
+
+// InjectDuplicateAccounting resends sess's Acct-Start immediately after the
+// first, both carrying the same Acct-Session-Id, so a test harness can
+// exercise FreeRADIUS's duplicate-detection window via the control API.
+func (s *Simulator) InjectDuplicateAccounting(ctx context.Context, sess *Session) error {
+	if err := s.sendAcctStart(ctx, sess); err != nil {
+		return err
+	}
+	return s.sendAcctStart(ctx, sess)
+}
+
+// acctSpanAttributes returns the span attributes common to every Accounting
+// exchange (Start/Interim/Stop), identifying which NAS, session, and
+// subscriber the span belongs to.
+func acctSpanAttributes(sess *Session) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("nas.ip", sess.BNG.IP.String()),
+		attribute.String("nas.name", sess.BNG.Name),
+		attribute.String("session.id", sess.SessionID),
+		attribute.String("subscriber.username", sess.Username),
+		attribute.String("profile.name", sess.Profile.Name),
+	}
+}
+
+func (s *Simulator) exchangeAcct(ctx context.Context, pkt *radius.Packet) error {
+	ctx, span := tracer.Start(ctx, "exchangeAcct")
+	defer span.End()
+
+	resp, err := s.doExchange(ctx, "acct", s.radiusAcctAddr, pkt)
 	if err != nil {
 		atomic.AddInt64(&s.stats.Errors, 1)
-		return fmt.Errorf("acct exchange: %w", err)
+		span.RecordError(err)
+		return err
 	}
 	if resp.Code != radius.CodeAccountingResponse {
 		atomic.AddInt64(&s.stats.Errors, 1)
-		return fmt.Errorf("unexpected code=%d", resp.Code)
+		metrics.Errors.WithLabelValues("acct").Inc()
+		err := fmt.Errorf("unexpected code=%d", resp.Code)
+		span.RecordError(err)
+		return err
 	}
 	return nil
 }
@@ -401,14 +701,27 @@ func (s *Simulator) exchangeAcct(pkt *radius.Packet) error {
 // ---------------------------------------------------------------------------
 
 func (s *Simulator) doConnect(sub *Subscriber) {
-	if err := s.sendAuth(sub.Username, sub.Password); err != nil {
+	s.doConnectOn(sub, &bngs[s.rng.Intn(len(bngs))])
+}
+
+// doConnectOn is doConnect against a caller-chosen chassis, split out so
+// scenario mode can pin a connect event to the bng= its timeline names.
+func (s *Simulator) doConnectOn(sub *Subscriber, bng *BNG) {
+	ctx, span := tracer.Start(context.Background(), "doConnect")
+	defer span.End()
+	span.SetAttributes(attribute.String("subscriber.username", sub.Username))
+
+	if err := s.sendAuth(ctx, bng, sub.Username, sub.Password); err != nil {
+		span.RecordError(err)
 		atomic.AddInt64(&s.stats.Rejects, 1)
+		metrics.Rejects.WithLabelValues(bng.Name, bng.Region, nasPortTypeLabel).Inc()
+		s.emitEvent("connect", bng.Name, "reject")
 		s.logEvent("REJECT", fmt.Sprintf("%-20s %v", sub.Username, err))
 		return
 	}
 	atomic.AddInt64(&s.stats.AuthOK, 1)
+	metrics.AuthOK.WithLabelValues(bng.Name, bng.Region, nasPortTypeLabel).Inc()
 
-	bng := &bngs[s.rng.Intn(len(bngs))]
 	prof := &profiles[s.rng.Intn(len(profiles))]
 	nasPort, nasPortID := s.genNASPortID(bng)
 	sess := &Session{
@@ -421,10 +734,13 @@ func (s *Simulator) doConnect(sub *Subscriber) {
 		FramedIP:  s.genFramedIP(),
 		MAC:       s.genMAC(),
 		Profile:   prof,
-		StartTime: time.Now(),
+		StartTime: s.clock.Now(),
 	}
 
-	if err := s.sendAcctStart(sess); err != nil {
+	span.SetAttributes(attribute.String("session.id", sess.SessionID))
+	if err := s.sendAcctStart(ctx, sess); err != nil {
+		span.RecordError(err)
+		s.emitEvent("connect", bng.Name, "error")
 		s.logEvent("ERROR", fmt.Sprintf("Start fail: %s - %v", sub.Username, err))
 		return
 	}
@@ -432,14 +748,23 @@ func (s *Simulator) doConnect(sub *Subscriber) {
 	s.mu.Lock()
 	s.sessions[sub.Username] = sess
 	s.mu.Unlock()
+	metrics.ActiveSessions.WithLabelValues(bng.Name, bng.Region).Inc()
 
 	atomic.AddInt64(&s.stats.Starts, 1)
+	metrics.AcctStarts.WithLabelValues(bng.Name, bng.Region, prof.Name).Inc()
+	s.emitEvent("connect", bng.Name, "ok")
 	s.logEvent("START", fmt.Sprintf("%-20s sid=%-16s nas=%-14s ip=%-16s port=%-14s prof=%s",
 		sub.Username, sess.SessionID, bng.Name, sess.FramedIP, sess.NASPortID, prof.Name))
 }
 
 func (s *Simulator) doInterim(sess *Session) {
-	if err := s.sendAcctInterim(sess); err != nil {
+	ctx, span := tracer.Start(context.Background(), "doInterim")
+	defer span.End()
+	span.SetAttributes(acctSpanAttributes(sess)...)
+
+	if err := s.sendAcctInterim(ctx, sess); err != nil {
+		span.RecordError(err)
+		s.emitEvent("interim", sess.BNG.Name, "error")
 		s.logEvent("ERROR", fmt.Sprintf("Interim fail: %s - %v", sess.Username, err))
 		return
 	}
@@ -447,13 +772,24 @@ func (s *Simulator) doInterim(sess *Session) {
 	atomic.AddInt64(&s.stats.Interims, 1)
 	atomic.AddInt64(&s.stats.TotalDL, sess.TotalDL)
 	atomic.AddInt64(&s.stats.TotalUL, sess.TotalUL)
+	metrics.AcctInterims.WithLabelValues(sess.BNG.Name, sess.BNG.Region, sess.Profile.Name).Inc()
+	s.emitEvent("interim", sess.BNG.Name, "ok")
 	s.logEvent("INTERIM", fmt.Sprintf("%-20s t=%5ds dl=%-12s ul=%-12s prof=%s",
 		sess.Username, sess.AcctTime, fmtBytes(sess.TotalDL), fmtBytes(sess.TotalUL), sess.Profile.Name))
 }
 
-func (s *Simulator) doDisconnect(sess *Session) {
-	cause := terminateCauses[s.rng.Intn(len(terminateCauses))]
-	if err := s.sendAcctStop(sess, cause); err != nil {
+// doDisconnect tears down sess with the given terminate cause, whether the
+// decision came from the random-disconnect phase of runCycle or an operator
+// RFC 5176 Disconnect-Message received by the CoA server.
+func (s *Simulator) doDisconnect(sess *Session, cause rfc2866.AcctTerminateCause) {
+	ctx, span := tracer.Start(context.Background(), "doDisconnect")
+	defer span.End()
+	span.SetAttributes(acctSpanAttributes(sess)...)
+	span.SetAttributes(attribute.String("radius.terminate_cause", cause.String()))
+
+	if err := s.sendAcctStop(ctx, sess, cause); err != nil {
+		span.RecordError(err)
+		s.emitEvent("disconnect", sess.BNG.Name, "error")
 		s.logEvent("ERROR", fmt.Sprintf("Stop fail: %s - %v", sess.Username, err))
 		return
 	}
@@ -461,8 +797,11 @@ func (s *Simulator) doDisconnect(sess *Session) {
 	s.mu.Lock()
 	delete(s.sessions, sess.Username)
 	s.mu.Unlock()
+	metrics.ActiveSessions.WithLabelValues(sess.BNG.Name, sess.BNG.Region).Dec()
 
 	atomic.AddInt64(&s.stats.Stops, 1)
+	metrics.AcctStops.WithLabelValues(sess.BNG.Name, sess.BNG.Region, sess.Profile.Name).Inc()
+	s.emitEvent("disconnect", sess.BNG.Name, "ok")
 	s.logEvent("STOP", fmt.Sprintf("%-20s cause=%-16s t=%5ds dl=%-12s ul=%s",
 		sess.Username, cause, sess.AcctTime, fmtBytes(sess.TotalDL), fmtBytes(sess.TotalUL)))
 }
@@ -472,7 +811,9 @@ func (s *Simulator) doDisconnect(sess *Session) {
 // ---------------------------------------------------------------------------
 
 func (s *Simulator) burstConnect() {
+	s.mu.Lock()
 	target := s.burstSize
+	s.mu.Unlock()
 	if target > len(s.subscribers) {
 		target = len(s.subscribers)
 	}
@@ -526,12 +867,14 @@ func (s *Simulator) runCycle() {
 
 	s.mu.Lock()
 	activeCount := len(s.sessions)
+	maxSubs := s.maxSubs
+	connectsPerCyc := s.connectsPerCyc
 	s.mu.Unlock()
 
 	// Phase 1: New connections (multiple per cycle, in parallel)
-	if activeCount < s.maxSubs {
-		toConnect := s.connectsPerCyc
-		available := s.maxSubs - activeCount
+	if activeCount < maxSubs {
+		toConnect := connectsPerCyc
+		available := maxSubs - activeCount
 		if toConnect > available {
 			toConnect = available
 		}
@@ -603,7 +946,8 @@ func (s *Simulator) runCycle() {
 			wg.Add(1)
 			go func(ss *Session) {
 				defer wg.Done()
-				s.doDisconnect(ss)
+				cause := terminateCauses[s.rng.Intn(len(terminateCauses))]
+				s.doDisconnect(ss, cause)
 			}(sess)
 		}
 		wg.Wait()
@@ -634,6 +978,13 @@ func (s *Simulator) drawDashboard() {
 		len(bngs), len(s.subscribers), s.cycleDuration, s.interimSecs, pps)
 	fmt.Printf("  Runtime: \033[1m%s\033[0m | Cycle: \033[1m%d\033[0m | Active: \033[1;32m%d\033[0m\n",
 		fmtDuration(runtime), atomic.LoadInt64(&s.stats.Cycles), active)
+	if authPPS, acctPPS := atomic.LoadInt64(&s.rateLimits.AuthPPS), atomic.LoadInt64(&s.rateLimits.AcctPPS); authPPS > 0 || acctPPS > 0 ||
+		atomic.LoadInt64(&s.rateLimits.LatencyMeanMs) > 0 || atomic.LoadInt64(&s.rateLimits.PacketLossPct) > 0 {
+		fmt.Printf("  Auth PPS: \033[1m%d\033[0m | Acct PPS: \033[1m%d\033[0m | Latency: \033[1m%d±%dms\033[0m | Loss: \033[1m%d%%\033[0m\n",
+			authPPS, acctPPS,
+			atomic.LoadInt64(&s.rateLimits.LatencyMeanMs), atomic.LoadInt64(&s.rateLimits.LatencyJitterMs),
+			atomic.LoadInt64(&s.rateLimits.PacketLossPct))
+	}
 	fmt.Println("\033[1;36m╠═══════════════════════════════════════════════════════════════════════════════════╣\033[0m")
 	fmt.Printf("  \033[32mAuth OK:\033[0m %-5d | \033[35mReject:\033[0m %-5d | \033[32mStart:\033[0m %-5d | \033[33mInterim:\033[0m %-5d | \033[31mStop:\033[0m %-5d | \033[31mErr:\033[0m %d\n",
 		atomic.LoadInt64(&s.stats.AuthOK), atomic.LoadInt64(&s.stats.Rejects),
@@ -701,7 +1052,7 @@ func (s *Simulator) shutdown() {
 		wg.Add(1)
 		go func(ss *Session) {
 			defer wg.Done()
-			s.doDisconnect(ss)
+			s.doDisconnect(ss, rfc2866.AcctTerminateCause_Value_NASReboot)
 		}(sess)
 	}
 	wg.Wait()
@@ -731,8 +1082,22 @@ func (s *Simulator) shutdown() {
 }
 
 func (s *Simulator) logEvent(level, msg string) {
-	if s.headless {
-		fmt.Printf("[%s] [%-7s] %s\n", time.Now().Format("15:04:05.000"), level, msg)
+	if !s.headless {
+		return
+	}
+	line := fmt.Sprintf("[%s] [%-7s] %s", s.clock.Now().Format("15:04:05.000"), level, msg)
+	for _, sink := range s.sinks {
+		sink.Log(line)
+	}
+}
+
+// emitEvent reports one lifecycle outcome (connect/interim/disconnect),
+// tagged by bng chassis and outcome, to every configured StatsSink —
+// unlike logEvent's text lines, this always fires, headless or not, so a
+// statsd/otlp sink sees traffic even with the TUI dashboard running.
+func (s *Simulator) emitEvent(name, bng, outcome string) {
+	for _, sink := range s.sinks {
+		sink.Event(name, bng, outcome)
 	}
 }
 
@@ -779,8 +1144,26 @@ func main() {
 	fmt.Println("\033[1;36m╚═══════════════════════════════════════════════════════╝\033[0m")
 	fmt.Println()
 
-	for _, arg := range os.Args[1:] {
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		fmt.Printf("[warn] tracing disabled: %v\n", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	var scenarioPath, speedFlag string
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
 		switch strings.ToLower(arg) {
+		case "--scenario":
+			i++
+			if i < len(os.Args) {
+				scenarioPath = os.Args[i]
+			}
+		case "--speed":
+			i++
+			if i < len(os.Args) {
+				speedFlag = os.Args[i]
+			}
 		case "--turbo":
 			os.Setenv("CYCLE_MS", "200")
 			os.Setenv("INTERIM_INTERVAL", "30")
@@ -804,19 +1187,32 @@ func main() {
 			os.Setenv("DISCONNECT_CHANCE", "2")
 		case "--headless":
 			os.Setenv("HEADLESS", "true")
+		case "--metrics":
+			os.Setenv("METRICS_ENABLED", "true")
 		case "--help":
-			fmt.Println("Usage: nokia-bng-simulator [--turbo|--fast|--gentle] [--headless]")
+			fmt.Println("Usage: nokia-bng-simulator [--turbo|--fast|--gentle] [--headless] [--metrics]")
+			fmt.Println("                           [--scenario path.yaml] [--speed 10x]")
 			fmt.Println()
 			fmt.Println("Presets:")
 			fmt.Println("  --turbo    200ms cycle, 30s interim, 40 subs, burst 30, 8 conn/cyc")
 			fmt.Println("  --fast     500ms cycle, 60s interim, 40 subs, burst 20, 5 conn/cyc")
 			fmt.Println("  --gentle   5s cycle, 300s interim, 10 subs, burst 5, 1 conn/cyc")
 			fmt.Println("  --headless Log-only mode, no TUI dashboard")
+			fmt.Println("  --metrics  Force the Prometheus endpoint on (it's already on by default)")
+			fmt.Println("  --scenario path.yaml  Run a deterministic event timeline on a virtual")
+			fmt.Println("                        clock instead of the random cycle loop, then exit")
+			fmt.Println("                        with the pass/fail count as a regression result")
+			fmt.Println("  --speed 10x           Pacing multiplier between scenario events")
+			fmt.Println("                        (default 1x = wall-clock real time)")
 			fmt.Println()
 			fmt.Println("Environment variables:")
 			fmt.Println("  RADIUS_HOST          FreeRADIUS host     (default: freeradius)")
 			fmt.Println("  RADIUS_SECRET        Shared secret       (default: testing123)")
 			fmt.Println("  PG_CONN              PostgreSQL DSN")
+			fmt.Println("  SUBSCRIBER_KAFKA_BROKERS  Comma-separated Kafka brokers; if set with")
+			fmt.Println("                            SUBSCRIBER_KAFKA_TOPIC, replaces the Postgres pull")
+			fmt.Println("                            with a live Debezium CDC feed over RadiusUsers")
+			fmt.Println("  SUBSCRIBER_KAFKA_TOPIC    Debezium CDC topic for RadiusUsers")
 			fmt.Println("  MAX_SUBSCRIBERS      Subscriber pool     (default: 40)")
 			fmt.Println("  CYCLE_MS             Cycle in ms         (default: 500)")
 			fmt.Println("  INTERIM_INTERVAL     Interim secs        (default: 60)")
@@ -824,12 +1220,33 @@ func main() {
 			fmt.Println("  BURST_SIZE           Initial burst       (default: 20)")
 			fmt.Println("  DISCONNECT_CHANCE    Disconnect % chance (default: 5)")
 			fmt.Println("  HEADLESS             true for log mode   (default: false)")
+			fmt.Println("  METRICS_ADDR         Prometheus /metrics (default: :9110)")
+			fmt.Println("  PROMETHEUS_LISTEN    Alias for METRICS_ADDR, takes precedence")
+			fmt.Println("  METRICS_ENABLED      Serve the /metrics endpoint (default: true)")
+			fmt.Println("  COA_ADDR             CoA/Disconnect addr (default: :3799)")
+			fmt.Println("  CONTROL_ADDR         REST control API addr (default: :9120)")
+			fmt.Println("  AUTH_PPS             Auth token-bucket rate, pkts/sec (default: 0 = unlimited)")
+			fmt.Println("  ACCT_PPS             Acct token-bucket rate, pkts/sec (default: 0 = unlimited)")
+			fmt.Println("  NET_LATENCY_MS       Simulated mean response latency  (default: 0)")
+			fmt.Println("  NET_JITTER_MS        +/- jitter added to NET_LATENCY_MS (default: 0)")
+			fmt.Println("  NET_LOSS_PCT         Simulated response loss %        (default: 0)")
+			fmt.Println("  RADIUS_TRANSPORT     udp or radsec       (default: udp)")
+			fmt.Println("  RADSEC_CERT          RadSec client cert (PEM)")
+			fmt.Println("  RADSEC_KEY           RadSec client key (PEM)")
+			fmt.Println("  RADSEC_CA            RadSec CA bundle (PEM)")
+			fmt.Println("  RADSEC_SERVERNAME    RadSec TLS SNI override")
+			fmt.Println("  OTEL_EXPORTER_OTLP_ENDPOINT  OTLP/gRPC collector addr")
+			fmt.Println("  OTEL_SERVICE_NAME            Service name in traces (default: nokia-bng-simulator)")
+			fmt.Println("  STATS_SINKS          Comma-separated stats sinks: stdout,statsd,otlp (default: stdout)")
+			fmt.Println("  STATSD_ADDR          StatsD/dogstatsd UDP addr (default: localhost:8125)")
 			os.Exit(0)
 		}
 	}
 
 	sim := NewSimulator()
 	sim.startTime = time.Now()
+	sim.sinks = newStatsSinks(context.Background())
+	defer closeStatsSinks(sim.sinks)
 
 	fmt.Printf("  Auth:        %s\n", sim.radiusAuthAddr)
 	fmt.Printf("  Acct:        %s\n", sim.radiusAcctAddr)
@@ -851,9 +1268,53 @@ func main() {
 		}
 	}
 
+	if scenarioPath != "" {
+		speed, err := ParseSpeed(speedFlag)
+		if err != nil {
+			fmt.Printf("[error] %v\n", err)
+			closeStatsSinks(sim.sinks)
+			os.Exit(2)
+		}
+		sc, err := LoadScenario(scenarioPath)
+		if err != nil {
+			fmt.Printf("[error] %v\n", err)
+			closeStatsSinks(sim.sinks)
+			os.Exit(2)
+		}
+
+		fmt.Printf("[scenario] Running %d events from %s at %gx speed\n", len(sc.Events), scenarioPath, speed)
+		pass, fail := RunScenario(sim, sc, speed)
+		fmt.Printf("[scenario] %d passed, %d failed\n", pass, fail)
+		if fail > 0 {
+			closeStatsSinks(sim.sinks)
+			os.Exit(1)
+		}
+		return
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	subEvents := sim.subscriberSrc.Watch(watchCtx)
+
+	coaServer := newCoAServer(sim)
+	go func() {
+		fmt.Printf("[init] CoA/Disconnect listener on %s\n", sim.coaAddr)
+		if err := coaServer.ListenAndServe(); err != nil {
+			fmt.Printf("[warn] CoA listener on %s stopped: %v\n", sim.coaAddr, err)
+		}
+	}()
+
+	controlAPI := newControlAPI(sim, sim.controlAddr)
+	go func() {
+		fmt.Printf("[init] Control API on %s\n", sim.controlAddr)
+		if err := controlAPI.ListenAndServe(); err != nil {
+			fmt.Printf("[warn] Control API on %s stopped: %v\n", sim.controlAddr, err)
+		}
+	}()
+
 	// Burst connect phase
 	sim.burstConnect()
 
@@ -866,13 +1327,23 @@ func main() {
 		select {
 		case <-sigCh:
 			ticker.Stop()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			controlAPI.Shutdown(shutdownCtx)
+			cancel()
 			sim.shutdown()
 			return
+		case ev, ok := <-subEvents:
+			if !ok {
+				subEvents = nil // source's feed ended; stop selecting on it
+				continue
+			}
+			sim.applySubscriberEvent(ev)
 		case <-ticker.C:
 			sim.runCycle()
 			if !sim.headless {
 				sim.drawDashboard()
 			}
+			ticker.Reset(sim.CycleDuration()) // picks up any control API config change
 		}
 	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"layeh.com/radius"
+)
+
+// RateLimits holds the live-tunable network-condition knobs applied around
+// every RADIUS exchange: per-stream packet-per-second caps enforced by a
+// token bucket on the send side, and artificial latency/jitter/loss applied
+// on the receive side to simulate a congested or lossy network. Every field
+// is read with atomic.LoadInt64 on the hot path, the same pattern s.stats
+// uses for its counters. The control API retunes AuthPPS, AcctPPS and the
+// latency/jitter/loss fields with atomic.StoreInt64 without a restart;
+// BurstPackets is set once at startup, and no signal handler changes any of
+// them.
+type RateLimits struct {
+	AuthPPS         int64 // 0 disables the auth token bucket
+	AcctPPS         int64 // 0 disables the acct token bucket
+	BurstPackets    int64 // token bucket capacity (burst allowance)
+	LatencyMeanMs   int64 // mean artificial delay added before a response is returned
+	LatencyJitterMs int64 // uniform +/- jitter added on top of LatencyMeanMs
+	PacketLossPct   int64 // 0-100 percent chance of dropping the response as "lost"
+}
+
+// defaultRateBurst is the token bucket's burst allowance when no limit has
+// been configured yet; it only matters once AuthPPS/AcctPPS are non-zero.
+const defaultRateBurst = 20
+
+// tokenBucket is a simple token-bucket rate limiter keyed off a pair of
+// atomically-adjustable fields (rate and burst), so RunScenario-style live
+// retuning via the control API takes effect on the very next Take.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	rate  *int64
+	burst *int64
+}
+
+func newTokenBucket(rate, burst *int64) *tokenBucket {
+	return &tokenBucket{last: time.Now(), rate: rate, burst: burst}
+}
+
+// Take blocks until a token is available, or ctx is done. A rate of 0 (the
+// default) disables limiting entirely.
+func (b *tokenBucket) Take(ctx context.Context) error {
+	for {
+		rate := atomic.LoadInt64(b.rate)
+		if rate <= 0 {
+			return nil
+		}
+		burst := atomic.LoadInt64(b.burst)
+		if burst <= 0 {
+			burst = 1
+		}
+
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * float64(rate)
+		b.last = now
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration(float64(time.Second) / float64(rate))
+		b.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// applyNetworkConditions simulates the lossy/congested network a token
+// bucket alone can't model: PacketLossPct has a chance to drop the response
+// outright, and LatencyMeanMs/LatencyJitterMs delay whatever's left before
+// it's handed back. The delay runs in its own goroutine so an early ctx
+// cancellation still returns promptly instead of blocking through the full
+// simulated delay.
+func (s *Simulator) applyNetworkConditions(ctx context.Context, resp *radius.Packet, err error) (*radius.Packet, error) {
+	if lossPct := atomic.LoadInt64(&s.rateLimits.PacketLossPct); lossPct > 0 && s.rng.Int63n(100) < lossPct {
+		metrics.PacketsDropped.Inc()
+		resp, err = nil, fmt.Errorf("simulated network loss")
+	}
+
+	meanMs := atomic.LoadInt64(&s.rateLimits.LatencyMeanMs)
+	jitterMs := atomic.LoadInt64(&s.rateLimits.LatencyJitterMs)
+	if meanMs <= 0 && jitterMs <= 0 {
+		return resp, err
+	}
+
+	delay := time.Duration(meanMs) * time.Millisecond
+	if jitterMs > 0 {
+		delay += time.Duration(s.rng.Int63n(2*jitterMs+1)-jitterMs) * time.Millisecond
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(delay)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return resp, err
+}
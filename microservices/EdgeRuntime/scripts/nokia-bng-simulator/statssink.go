@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// StatsSink is a pluggable destination for the simulator's stats/log
+// emission, selected by STATS_SINKS. Log carries logEvent's pre-formatted,
+// headless-only text lines (stdout's job); Event carries every lifecycle
+// outcome — connect/interim/disconnect, tagged by bng chassis and outcome —
+// for sinks that aggregate or forward to a metrics backend instead of
+// printing.
+type StatsSink interface {
+	Log(line string)
+	Event(name, bng, outcome string)
+	Close() error
+}
+
+// newStatsSinks builds the sinks named in STATS_SINKS (comma-separated;
+// default "stdout"). A sink that fails to initialize is logged and skipped
+// rather than failing the whole simulator over a stats backend.
+func newStatsSinks(ctx context.Context) []StatsSink {
+	names := strings.Split(envOr("STATS_SINKS", "stdout"), ",")
+	sinks := make([]StatsSink, 0, len(names))
+	for _, name := range names {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "", "stdout":
+			sinks = append(sinks, stdoutSink{})
+		case "statsd":
+			sink, err := newStatsDSink(envOr("STATSD_ADDR", "localhost:8125"))
+			if err != nil {
+				fmt.Printf("[warn] statsd sink disabled: %v\n", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+		case "otlp":
+			sink, err := newOTLPMetricSink(ctx)
+			if err != nil {
+				fmt.Printf("[warn] otlp metric sink disabled: %v\n", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+		default:
+			fmt.Printf("[warn] unknown STATS_SINKS entry %q\n", name)
+		}
+	}
+	return sinks
+}
+
+// closeStatsSinks closes every sink, logging (rather than failing) any
+// error so a stuck exporter doesn't block shutdown.
+func closeStatsSinks(sinks []StatsSink) {
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			fmt.Printf("[warn] stats sink close: %v\n", err)
+		}
+	}
+}
+
+// stdoutSink is the simulator's original behavior: logEvent's headless-only
+// text lines. It carries no structured Event output of its own — the lines
+// it prints already show every outcome as it happens.
+type stdoutSink struct{}
+
+func (stdoutSink) Log(line string)                 { fmt.Println(line) }
+func (stdoutSink) Event(name, bng, outcome string) {}
+func (stdoutSink) Close() error                     { return nil }
+
+// statsDSink forwards lifecycle events to a dogstatsd-compatible listener
+// as UDP counter increments tagged by bng chassis and outcome.
+type statsDSink struct {
+	conn net.Conn
+}
+
+func newStatsDSink(addr string) (*statsDSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial statsd %s: %w", addr, err)
+	}
+	return &statsDSink{conn: conn}, nil
+}
+
+func (*statsDSink) Log(line string) {}
+
+// Event sends "bngsim.<name>:1|c|#bng:<bng>,outcome:<outcome>", the
+// dogstatsd tag extension most StatsD-compatible collectors accept.
+func (s *statsDSink) Event(name, bng, outcome string) {
+	line := fmt.Sprintf("bngsim.%s:1|c|#bng:%s,outcome:%s\n", name, bng, outcome)
+	if _, err := s.conn.Write([]byte(line)); err != nil {
+		fmt.Printf("[warn] statsd write: %v\n", err)
+	}
+}
+
+func (s *statsDSink) Close() error { return s.conn.Close() }
+
+// otlpMetricSink forwards lifecycle events as an OTLP/gRPC metric counter,
+// so they land next to the doConnect/doInterim/doDisconnect spans tracing.go
+// already emits and can be correlated in the same backend (Tempo/Jaeger +
+// a metrics store, or an all-in-one like Grafana Cloud).
+type otlpMetricSink struct {
+	counter  metric.Int64Counter
+	shutdown func(context.Context) error
+}
+
+func newOTLPMetricSink(ctx context.Context) (*otlpMetricSink, error) {
+	exporter, err := otlpmetricgrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP metric exporter: %w", err)
+	}
+
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+	otel.SetMeterProvider(mp)
+
+	counter, err := mp.Meter("nokia-bng-simulator").Int64Counter(
+		"bngsim.events",
+		metric.WithDescription("Simulator lifecycle events, labeled by event name, bng chassis, and outcome."),
+	)
+	if err != nil {
+		mp.Shutdown(ctx)
+		return nil, fmt.Errorf("create OTLP counter: %w", err)
+	}
+
+	return &otlpMetricSink{counter: counter, shutdown: mp.Shutdown}, nil
+}
+
+func (*otlpMetricSink) Log(line string) {}
+
+func (s *otlpMetricSink) Event(name, bng, outcome string) {
+	s.counter.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("event", name),
+		attribute.String("bng", bng),
+		attribute.String("outcome", outcome),
+	))
+}
+
+func (s *otlpMetricSink) Close() error { return s.shutdown(context.Background()) }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/segmentio/kafka-go"
+)
+
+// SubscriberEventType distinguishes the two kinds of provisioning change a
+// SubscriberSource can report on its Watch channel.
+type SubscriberEventType int
+
+const (
+	SubscriberUpsert SubscriberEventType = iota
+	SubscriberDelete
+)
+
+// SubscriberEvent is a single provisioning change: an Upsert carries the new
+// Sub, a Delete carries only the Username that was removed or disabled.
+type SubscriberEvent struct {
+	Type     SubscriberEventType
+	Sub      Subscriber
+	Username string
+}
+
+// SubscriberSource decouples the simulator's subscriber pool from where it
+// comes from: Load fills the pool at startup, Watch streams provisioning
+// changes afterwards so the pool tracks RadiusUsers in real time instead of
+// going stale until the process restarts.
+type SubscriberSource interface {
+	// Load returns the initial subscriber pool.
+	Load(ctx context.Context) ([]Subscriber, error)
+	// Watch streams provisioning changes until ctx is canceled, closing the
+	// channel when it gives up. A source with no live feed may return nil.
+	Watch(ctx context.Context) <-chan SubscriberEvent
+}
+
+// newSubscriberSource picks the pool's source: a Debezium-style CDC topic on
+// Kafka if SUBSCRIBER_KAFKA_BROKERS and SUBSCRIBER_KAFKA_TOPIC are both set,
+// otherwise the original one-shot Postgres pull.
+func newSubscriberSource(pgConnStr string, maxSubs int) SubscriberSource {
+	brokers := envOr("SUBSCRIBER_KAFKA_BROKERS", "")
+	topic := envOr("SUBSCRIBER_KAFKA_TOPIC", "")
+	if brokers != "" && topic != "" {
+		return newKafkaSubscriberSource(strings.Split(brokers, ","), topic)
+	}
+	return &postgresSubscriberSource{pgConnStr: pgConnStr, maxSubs: maxSubs}
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL — one-shot pull, no live feed
+// ---------------------------------------------------------------------------
+
+// postgresSubscriberSource is the original startup-only pull from
+// RadiusUsers; it has no way to learn about changes afterwards, so Watch
+// returns nil.
+type postgresSubscriberSource struct {
+	pgConnStr string
+	maxSubs   int
+}
+
+func (p *postgresSubscriberSource) Load(ctx context.Context) ([]Subscriber, error) {
+	fmt.Println("[init] Loading subscribers from PostgreSQL...")
+	db, err := sql.Open("postgres", p.pgConnStr)
+	if err != nil {
+		return nil, fmt.Errorf("pg connect: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT "Username", "Password" FROM "RadiusUsers"
+		 WHERE "Enabled"=true AND "IsDeleted"=false AND "Password" IS NOT NULL
+		 ORDER BY random() LIMIT $1`, p.maxSubs)
+	if err != nil {
+		return nil, fmt.Errorf("pg query: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []Subscriber
+	for rows.Next() {
+		var sub Subscriber
+		if err := rows.Scan(&sub.Username, &sub.Password); err != nil {
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("no subscribers found")
+	}
+	fmt.Printf("[init] Loaded %d subscribers from CDC pool\n", len(subs))
+	return subs, nil
+}
+
+func (p *postgresSubscriberSource) Watch(ctx context.Context) <-chan SubscriberEvent {
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Kafka — Debezium-style CDC topic
+// ---------------------------------------------------------------------------
+
+// kafkaSubscriberSource reads a Debezium CDC topic over RadiusUsers: Load
+// drains whatever the topic already holds into a snapshot, Watch keeps
+// reading from the same consumer group and turns every further message into
+// an Upsert or Delete event.
+type kafkaSubscriberSource struct {
+	reader *kafka.Reader
+}
+
+func newKafkaSubscriberSource(brokers []string, topic string) *kafkaSubscriberSource {
+	return &kafkaSubscriberSource{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			GroupID: "nokia-bng-simulator",
+		}),
+	}
+}
+
+// subscriberSnapshotGap is how long Load waits for a new message before it
+// decides the topic's backlog is drained and the snapshot is complete.
+const subscriberSnapshotGap = 2 * time.Second
+
+func (k *kafkaSubscriberSource) Load(ctx context.Context) ([]Subscriber, error) {
+	fmt.Println("[init] Loading subscribers from Kafka CDC topic...")
+	pool := make(map[string]Subscriber)
+
+	for {
+		gapCtx, cancel := context.WithTimeout(ctx, subscriberSnapshotGap)
+		msg, err := k.reader.ReadMessage(gapCtx)
+		cancel()
+		if err != nil {
+			break // backlog drained (or ctx canceled): snapshot is whatever we have
+		}
+		ev, err := decodeCDCEvent(msg.Value)
+		if err != nil {
+			continue
+		}
+		switch ev.Type {
+		case SubscriberUpsert:
+			pool[ev.Sub.Username] = ev.Sub
+		case SubscriberDelete:
+			delete(pool, ev.Username)
+		}
+	}
+	if len(pool) == 0 {
+		return nil, fmt.Errorf("no subscribers found on CDC topic")
+	}
+
+	subs := make([]Subscriber, 0, len(pool))
+	for _, sub := range pool {
+		subs = append(subs, sub)
+	}
+	fmt.Printf("[init] Loaded %d subscribers from CDC pool\n", len(subs))
+	return subs, nil
+}
+
+func (k *kafkaSubscriberSource) Watch(ctx context.Context) <-chan SubscriberEvent {
+	events := make(chan SubscriberEvent)
+	go func() {
+		defer close(events)
+		for {
+			msg, err := k.reader.ReadMessage(ctx)
+			if err != nil {
+				return // ctx canceled or reader closed
+			}
+			ev, err := decodeCDCEvent(msg.Value)
+			if err != nil {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// debeziumEnvelope is the subset of a Debezium RadiusUsers change event this
+// simulator cares about: the row's state before and after the change, and
+// the operation that produced it (c=create, r=read/snapshot, u=update,
+// d=delete).
+type debeziumEnvelope struct {
+	Payload struct {
+		Op     string         `json:"op"`
+		Before *subscriberRow `json:"before"`
+		After  *subscriberRow `json:"after"`
+	} `json:"payload"`
+}
+
+type subscriberRow struct {
+	Username  string `json:"Username"`
+	Password  string `json:"Password"`
+	Enabled   bool   `json:"Enabled"`
+	IsDeleted bool   `json:"IsDeleted"`
+}
+
+// decodeCDCEvent turns one Debezium change event into a SubscriberEvent. A
+// delete, or an update that disables/soft-deletes the row, becomes a
+// SubscriberDelete so the caller force-disconnects any active session.
+func decodeCDCEvent(raw []byte) (SubscriberEvent, error) {
+	var env debeziumEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return SubscriberEvent{}, fmt.Errorf("decode CDC message: %w", err)
+	}
+
+	after := env.Payload.After
+	if env.Payload.Op == "d" || after == nil || !after.Enabled || after.IsDeleted {
+		username := ""
+		switch {
+		case env.Payload.Before != nil:
+			username = env.Payload.Before.Username
+		case after != nil:
+			username = after.Username
+		}
+		return SubscriberEvent{Type: SubscriberDelete, Username: username}, nil
+	}
+
+	return SubscriberEvent{
+		Type: SubscriberUpsert,
+		Sub:  Subscriber{Username: after.Username, Password: after.Password},
+	}, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// tracer emits the doConnect/doInterim/doDisconnect spans and their
+// sendAuth/sendAcctStart/sendAcctInterim/sendAcctStop/radius.Exchange
+// children. It's a package var, re-pointed at the real TracerProvider's
+// Tracer once setupTracing succeeds; until then (or if it never runs) it's
+// otel's global no-op tracer, so instrumentation is always safe to call.
+var tracer = otel.Tracer("nokia-bng-simulator")
+
+// setupTracing builds an OTLP/gRPC span exporter and registers it as the
+// global TracerProvider. The exporter and resource both read the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT/OTEL_SERVICE_NAME env vars themselves; the
+// attribute set here is only the default service name when OTEL_SERVICE_NAME
+// is unset. The returned func flushes pending spans and should be deferred.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return noop, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceNameKey.String("nokia-bng-simulator")),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return noop, fmt.Errorf("build OTel resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	tracer = tp.Tracer("nokia-bng-simulator")
+
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"layeh.com/radius"
+)
+
+// maxRadiusPacketSize is the largest packet RFC 2865 §3 allows; RadSec reuses
+// the same Length field for framing (RFC 6613 §2.1), so it doubles as the
+// stream read limit.
+const maxRadiusPacketSize = 4096
+
+// Transport is how sendAuth/exchangeAcct put a packet on the wire, so the
+// simulator can be pointed at a RadSec proxy without touching call sites.
+type Transport interface {
+	Exchange(ctx context.Context, addr string, pkt *radius.Packet) (*radius.Packet, error)
+}
+
+// newTransport selects the wire protocol from RADIUS_TRANSPORT (default
+// "udp"); "radsec" speaks RADIUS over TLS (RFC 6614) instead.
+func newTransport() (Transport, error) {
+	switch mode := envOr("RADIUS_TRANSPORT", "udp"); mode {
+	case "udp", "":
+		return newBatchUDPTransport(), nil
+	case "radsec":
+		return newRadSecTransport()
+	default:
+		return nil, fmt.Errorf("unknown RADIUS_TRANSPORT %q (want udp or radsec)", mode)
+	}
+}
+
+// udpTransport is the original one-socket-per-request path via
+// radius.Exchange. batchUDPTransport (udptransport.go) replaced it as the
+// default "udp" mode; this now only backstops newTransport when it returns
+// an error (a RadSec config error or an unknown RADIUS_TRANSPORT).
+type udpTransport struct{}
+
+func (udpTransport) Exchange(ctx context.Context, addr string, pkt *radius.Packet) (*radius.Packet, error) {
+	return radius.Exchange(ctx, pkt, addr)
+}
+
+// radSecTransport exchanges packets over a persistent TLS connection per
+// destination address (RFC 6614), reusing each packet's own RADIUS Length
+// header for stream framing (RFC 6613 §2.1) rather than UDP datagram
+// boundaries.
+type radSecTransport struct {
+	tlsConfig *tls.Config
+
+	mu    sync.Mutex
+	conns map[string]net.Conn
+}
+
+// newRadSecTransport builds a radSecTransport from RADSEC_CERT, RADSEC_KEY,
+// RADSEC_CA, and RADSEC_SERVERNAME.
+func newRadSecTransport() (*radSecTransport, error) {
+	tlsConfig := &tls.Config{ServerName: envOr("RADSEC_SERVERNAME", "")}
+
+	if certFile, keyFile := envOr("RADSEC_CERT", ""), envOr("RADSEC_KEY", ""); certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load RadSec client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile := envOr("RADSEC_CA", ""); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read RadSec CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &radSecTransport{tlsConfig: tlsConfig, conns: make(map[string]net.Conn)}, nil
+}
+
+// Exchange writes pkt on the pooled connection for addr, dialing and
+// TLS-handshaking one on first use, and decodes the framed reply.
+func (t *radSecTransport) Exchange(ctx context.Context, addr string, pkt *radius.Packet) (*radius.Packet, error) {
+	wire, err := pkt.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encode RadSec packet: %w", err)
+	}
+
+	conn, err := t.connFor(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(wire); err != nil {
+		t.drop(addr)
+		return nil, fmt.Errorf("RadSec write to %s: %w", addr, err)
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		t.drop(addr)
+		return nil, fmt.Errorf("RadSec read length header from %s: %w", addr, err)
+	}
+	length := int(header[2])<<8 | int(header[3])
+	if length < 20 || length > maxRadiusPacketSize {
+		t.drop(addr)
+		return nil, fmt.Errorf("RadSec invalid length field %d from %s", length, addr)
+	}
+
+	body := make([]byte, length)
+	copy(body, header[:])
+	if _, err := io.ReadFull(conn, body[4:]); err != nil {
+		t.drop(addr)
+		return nil, fmt.Errorf("RadSec read body from %s: %w", addr, err)
+	}
+
+	resp, err := radius.Parse(body, pkt.Secret)
+	if err != nil {
+		t.drop(addr)
+		return nil, fmt.Errorf("RadSec decode response from %s: %w", addr, err)
+	}
+	return resp, nil
+}
+
+// connFor returns the pooled connection for addr, dialing and TLS-
+// handshaking a new one on first use or after drop.
+func (t *radSecTransport) connFor(ctx context.Context, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if conn, ok := t.conns[addr]; ok {
+		return conn, nil
+	}
+
+	tcpConn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("RadSec dial %s: %w", addr, err)
+	}
+	tlsConn := tls.Client(tcpConn, t.tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		tcpConn.Close()
+		return nil, fmt.Errorf("RadSec TLS handshake with %s: %w", addr, err)
+	}
+
+	t.conns[addr] = tlsConn
+	return tlsConn, nil
+}
+
+// drop closes and forgets the pooled connection for addr so the next
+// Exchange redials.
+func (t *radSecTransport) drop(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.conns[addr]; ok {
+		conn.Close()
+		delete(t.conns, addr)
+	}
+}
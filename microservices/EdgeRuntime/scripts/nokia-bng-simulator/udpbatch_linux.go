@@ -0,0 +1,37 @@
+//go:build linux
+
+package main
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+)
+
+// reusePortControl sets SO_REUSEPORT on a bngConn's listening socket so
+// several Simulator instances (or, in a future worker-per-core split, several
+// writer goroutines) can share the same BNG source IP:port.
+func reusePortControl(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// writeBatch flushes batch to remote in one syscall via
+// ipv4.PacketConn.WriteBatch, the same amortization WireGuard uses for
+// GSO/batched sends.
+func writeBatch(conn *net.UDPConn, remote *net.UDPAddr, batch [][]byte) error {
+	pc := ipv4.NewPacketConn(conn)
+	msgs := make([]ipv4.Message, len(batch))
+	for i, wire := range batch {
+		msgs[i] = ipv4.Message{Buffers: [][]byte{wire}, Addr: remote}
+	}
+	_, err := pc.WriteBatch(msgs, 0)
+	return err
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package main
+
+import (
+	"net"
+	"syscall"
+)
+
+// reusePortControl is a no-op outside Linux; SO_REUSEPORT support varies
+// enough across BSD/Darwin/Windows that it isn't worth chasing here since
+// the simulator's real deployment target is Linux containers.
+func reusePortControl(_, _ string, _ syscall.RawConn) error {
+	return nil
+}
+
+// writeBatch falls back to one WriteToUDP per datagram; ipv4.WriteBatch is
+// Linux-only (it wraps recvmmsg/sendmmsg).
+func writeBatch(conn *net.UDPConn, remote *net.UDPAddr, batch [][]byte) error {
+	var firstErr error
+	for _, wire := range batch {
+		if _, err := conn.WriteToUDP(wire, remote); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
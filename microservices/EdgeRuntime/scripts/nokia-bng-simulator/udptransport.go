@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+// udpWriters is the size of the small writer pool draining each bngConn's
+// outbound queue; kept low since WriteBatch already amortizes the syscall
+// cost per goroutine.
+const udpWriters = 4
+
+// udpBatchSize caps how many queued datagrams a single writer collects
+// before flushing, trading a little latency for fewer WriteBatch calls.
+const udpBatchSize = 64
+
+// pendingTTL is how long an in-flight exchange is kept before the janitor
+// goroutine reclaims it, covering requests whose reply never arrives.
+const pendingTTL = 5 * time.Second
+
+// batchUDPTransport replaces one-socket-per-request radius.Exchange calls
+// with a persistent, SO_REUSEPORT'd *net.UDPConn per BNG chassis so the
+// packet's NAS-IP-Address actually matches its source address, and a small
+// writer pool that batches datagrams (golang.org/x/net/ipv4.WriteBatch on
+// Linux, a WriteToUDP loop elsewhere) to push well past the ~1 syscall/pkt
+// ceiling of one-shot radius.Exchange.
+type batchUDPTransport struct {
+	mu    sync.Mutex
+	conns map[string]*bngConn // keyed by "localIP|remoteAddr"
+}
+
+func newBatchUDPTransport() *batchUDPTransport {
+	return &batchUDPTransport{conns: make(map[string]*bngConn)}
+}
+
+// Exchange sends pkt from the local BNG it already carries as its
+// NAS-IP-Address attribute (set by sendAuth/sendAcctStart before this is
+// called) and returns the correlated reply.
+func (t *batchUDPTransport) Exchange(ctx context.Context, addr string, pkt *radius.Packet) (*radius.Packet, error) {
+	localIP, _ := rfc2865.NASIPAddress_Get(pkt)
+
+	conn, err := t.connFor(localIP, addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn.exchange(ctx, pkt)
+}
+
+func (t *batchUDPTransport) connFor(localIP net.IP, addr string) (*bngConn, error) {
+	key := localIP.String() + "|" + addr
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.conns[key]; ok {
+		return c, nil
+	}
+
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", addr, err)
+	}
+
+	c, err := newBNGConn(localIP, remote)
+	if err != nil {
+		return nil, err
+	}
+	t.conns[key] = c
+	return c, nil
+}
+
+// pendingExchange is an in-flight request awaiting its reply. Since a RADIUS
+// reply only echoes the Identifier (never the request Authenticator), it is
+// looked up by Identifier alone; the stashed Authenticator is what verifies
+// the reply actually came from the secret holder once it arrives.
+type pendingExchange struct {
+	authenticator [16]byte
+	secret        []byte
+	replyCh       chan pendingResult
+	queuedAt      time.Time
+}
+
+type pendingResult struct {
+	resp *radius.Packet
+	err  error
+}
+
+// bngConn is one BNG chassis's persistent, SO_REUSEPORT'd socket: an
+// outbound queue drained by a small batching writer pool, a reader loop that
+// demuxes replies by Identifier, and a janitor that reclaims requests whose
+// reply never shows up.
+type bngConn struct {
+	conn   *net.UDPConn
+	remote *net.UDPAddr
+	out    chan outboundDatagram
+
+	mu      sync.Mutex
+	pending [256]*pendingExchange // indexed by RADIUS Identifier
+
+	closeCh chan struct{}
+}
+
+type outboundDatagram struct {
+	wire []byte
+}
+
+func newBNGConn(localIP net.IP, remote *net.UDPAddr) (*bngConn, error) {
+	lc := net.ListenConfig{Control: reusePortControl}
+	pc, err := lc.ListenPacket(context.Background(), "udp", (&net.UDPAddr{IP: localIP, Port: 0}).String())
+	if err != nil {
+		return nil, fmt.Errorf("bind BNG socket on %s: %w", localIP, err)
+	}
+	udpConn := pc.(*net.UDPConn)
+
+	c := &bngConn{
+		conn:    udpConn,
+		remote:  remote,
+		out:     make(chan outboundDatagram, 4096),
+		closeCh: make(chan struct{}),
+	}
+
+	for i := 0; i < udpWriters; i++ {
+		go c.writeLoop()
+	}
+	go c.readLoop()
+	go c.janitor()
+
+	return c, nil
+}
+
+// exchange enqueues pkt's wire bytes and blocks until its reply is demuxed
+// off the socket or ctx expires; a failed write is only counted in metrics,
+// so the caller then waits for ctx.
+func (c *bngConn) exchange(ctx context.Context, pkt *radius.Packet) (*radius.Packet, error) {
+	wire, err := pkt.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encode packet: %w", err)
+	}
+
+	pending := &pendingExchange{
+		authenticator: pkt.Authenticator,
+		secret:        pkt.Secret,
+		replyCh:       make(chan pendingResult, 1),
+		queuedAt:      time.Now(),
+	}
+
+	id := wire[1]
+	c.mu.Lock()
+	c.pending[id] = pending
+	c.mu.Unlock()
+
+	select {
+	case c.out <- outboundDatagram{wire: wire}:
+	case <-ctx.Done():
+		c.clearPending(id, pending)
+		return nil, ctx.Err()
+	}
+
+	select {
+	case result := <-pending.replyCh:
+		return result.resp, result.err
+	case <-ctx.Done():
+		c.clearPending(id, pending)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *bngConn) clearPending(id byte, want *pendingExchange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending[id] == want {
+		c.pending[id] = nil
+	}
+}
+
+// writeLoop drains c.out, collecting up to udpBatchSize datagrams before
+// flushing them with writeBatch (WriteBatch on Linux, WriteToUDP elsewhere).
+func (c *bngConn) writeLoop() {
+	batch := make([][]byte, 0, udpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := writeBatch(c.conn, c.remote, batch); err != nil {
+			metrics.Errors.WithLabelValues("udp_write").Inc()
+		}
+		batch = batch[:0]
+	}
+
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case d := <-c.out:
+			batch = append(batch, d.wire)
+		drain:
+			for len(batch) < udpBatchSize {
+				select {
+				case d := <-c.out:
+					batch = append(batch, d.wire)
+				default:
+					break drain
+				}
+			}
+			flush()
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// readLoop demuxes every inbound datagram to the pending exchange matching
+// its Identifier, verifying the reply against that exchange's stashed
+// request Authenticator and secret.
+func (c *bngConn) readLoop() {
+	buf := make([]byte, maxRadiusPacketSize)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			select {
+			case <-c.closeCh:
+				return
+			default:
+				metrics.Errors.WithLabelValues("udp_read").Inc()
+				continue
+			}
+		}
+		if n < 20 {
+			continue
+		}
+		id := buf[1]
+
+		c.mu.Lock()
+		pending := c.pending[id]
+		c.pending[id] = nil
+		c.mu.Unlock()
+		if pending == nil {
+			continue // no in-flight request for this Identifier; stray or late reply
+		}
+
+		resp, err := radius.Parse(append([]byte(nil), buf[:n]...), pending.secret)
+		if err == nil && !resp.IsAuthenticResponse(append([]byte(nil), pending.authenticator[:]...), pending.secret) {
+			err = fmt.Errorf("response authenticator mismatch")
+		}
+		pending.replyCh <- pendingResult{resp: resp, err: err}
+	}
+}
+
+// janitor clears pending slots older than pendingTTL whose reply never
+// arrived. It only frees the Identifier slot: nothing is sent on the
+// exchange's replyCh, so a caller still blocked in exchange() keeps waiting
+// until its own ctx is done.
+func (c *bngConn) janitor() {
+	ticker := time.NewTicker(pendingTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			c.mu.Lock()
+			for id, p := range c.pending {
+				if p != nil && now.Sub(p.queuedAt) > pendingTTL {
+					c.pending[id] = nil
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ─── Adaptive AIMD rate controller ──────────────────────────────────────────
+//
+// rampSend's default mode fires a linear ramp at whatever RPS the operator
+// guessed. -adaptive replaces that guess with a TCP-like additive-increase/
+// multiplicative-decrease search: grow the target RPS by +aimdIncrease every
+// second the phase's p95 latency and error rate both stay under -slo-p95/
+// -slo-err, and cut it by aimdDecreaseFactor every second either SLO is
+// violated. This answers "what's the real capacity of this stack" instead
+// of "did it survive the guess".
+
+// aimdDecreaseFactor is the multiplicative backoff applied to the target RPS
+// every second either SLO is breached.
+const aimdDecreaseFactor = 0.7
+
+// AIMDSample records the controller's target RPS at one tick, building the
+// RPS-vs-time trajectory the final report prints.
+type AIMDSample struct {
+	Elapsed time.Duration
+	RPS     float64
+}
+
+// AIMDReport summarizes an -adaptive rampSend run for the final report: the
+// highest target RPS the controller held at any tick, how many times it
+// backed off, and the full RPS-vs-time trajectory.
+type AIMDReport struct {
+	MaxRPS   float64
+	Backoffs int
+	Samples  []AIMDSample
+}
+
+// Print renders the RPS-vs-time trajectory as a bar per sample, hey/boom
+// style like LiveStats.PrintHistogram, so a backoff is visible as a dip.
+func (r AIMDReport) Print() {
+	if len(r.Samples) == 0 {
+		return
+	}
+	var max float64
+	for _, s := range r.Samples {
+		if s.RPS > max {
+			max = s.RPS
+		}
+	}
+	for _, s := range r.Samples {
+		fmt.Printf("    %6s [%6.0f/s]\t%s\n", fmtDur(s.Elapsed.Round(time.Second)), s.RPS, rpsBar(s.RPS, max))
+	}
+}
+
+// rpsBar renders v as a block-character bar scaled against max.
+func rpsBar(v, max float64) string {
+	if max == 0 {
+		return ""
+	}
+	const width = 40
+	return strings.Repeat("■", int(v*width/max))
+}
+
+// aimdController runs the AIMD search described above. The send loop reads
+// Target() each interval rather than computing RPS itself; a background
+// goroutine (see runAIMD) calls Tick once a second against the phase's live
+// stats and writes the adjusted target back into the same atomic variable.
+type aimdController struct {
+	target atomic.Int64 // whole RPS, read by the send loop every interval
+
+	increase float64
+	sloP95   time.Duration
+	sloErr   float64
+
+	mu       sync.Mutex
+	maxRPS   float64
+	backoffs int
+	samples  []AIMDSample
+}
+
+// newAIMDController builds a controller starting at startRPS and targeting
+// the given SLOs, growing by increase RPS/sec while under both.
+func newAIMDController(startRPS int, increase float64, sloP95 time.Duration, sloErr float64) *aimdController {
+	c := &aimdController{increase: increase, sloP95: sloP95, sloErr: sloErr, maxRPS: float64(startRPS)}
+	c.target.Store(int64(startRPS))
+	return c
+}
+
+// Target returns the controller's current target RPS.
+func (c *aimdController) Target() float64 {
+	return float64(c.target.Load())
+}
+
+// Tick evaluates stats' current p95/error-rate against the controller's
+// SLOs, adjusts the target RPS, and records a trajectory sample.
+func (c *aimdController) Tick(elapsed time.Duration, stats *LiveStats) {
+	total, _, _, errors, _, _ := stats.Snapshot()
+	_, p95, _ := stats.Percentiles()
+
+	errRate := 0.0
+	if total > 0 {
+		errRate = float64(errors) / float64(total)
+	}
+
+	cur := c.Target()
+	next := cur + c.increase
+	backedOff := false
+	if p95 >= c.sloP95 || errRate >= c.sloErr {
+		next = cur * aimdDecreaseFactor
+		backedOff = true
+	}
+	if next < 1 {
+		next = 1
+	}
+	c.target.Store(int64(next))
+
+	c.mu.Lock()
+	if cur > c.maxRPS {
+		c.maxRPS = cur
+	}
+	if backedOff {
+		c.backoffs++
+	}
+	c.samples = append(c.samples, AIMDSample{Elapsed: elapsed, RPS: cur})
+	c.mu.Unlock()
+}
+
+// Report snapshots the controller's trajectory for the final report.
+func (c *aimdController) Report() AIMDReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	samples := make([]AIMDSample, len(c.samples))
+	copy(samples, c.samples)
+	return AIMDReport{MaxRPS: c.maxRPS, Backoffs: c.backoffs, Samples: samples}
+}
+
+// runAIMD ticks aimd once a second against stats until ctx is canceled.
+// rampSend runs this in its own goroutine alongside the send loop whenever
+// -adaptive is set.
+func runAIMD(ctx context.Context, aimd *aimdController, stats *LiveStats) {
+	start := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			aimd.Tick(time.Since(start), stats)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AlarmType identifies which SLO an AlarmRule guards.
+type AlarmType string
+
+const (
+	AlarmErrorRate    AlarmType = "ERROR_RATE"
+	AlarmLatencySLO   AlarmType = "LATENCY_SLO"
+	AlarmTimeoutStorm AlarmType = "TIMEOUT_STORM"
+)
+
+// AlarmPolicy is what happens the first time a rule's threshold has been
+// continuously breached for its Window.
+type AlarmPolicy string
+
+const (
+	PolicyWarn     AlarmPolicy = "warn"     // log only
+	PolicyThrottle AlarmPolicy = "throttle" // halve the phase's send rate via rpsGovernor
+	PolicyAbort    AlarmPolicy = "abort"    // cancel the phase's context
+)
+
+// AlarmRule is one configured SLO threshold, e.g. -alarm-p99=500ms. Only the
+// threshold field matching Type is consulted.
+type AlarmRule struct {
+	Type   AlarmType
+	Policy AlarmPolicy
+	Window time.Duration // how long the threshold must stay crossed before it fires
+
+	ErrorRateThreshold   float64       // ERROR_RATE: fraction of requests, 0-1
+	LatencyThreshold     time.Duration // LATENCY_SLO: p99 ceiling
+	TimeoutRateThreshold float64       // TIMEOUT_STORM: timeouts/sec
+}
+
+// Alarm records one rule having fired during a phase.
+type Alarm struct {
+	Type    AlarmType
+	Policy  AlarmPolicy
+	Phase   string
+	FiredAt time.Duration // elapsed time into the phase when it fired
+	Detail  string        // human-readable summary for the verdict block
+}
+
+// tickInput is the subset of a phase's live state an AlarmStore needs to
+// evaluate its rules; startProgressTicker builds one from LiveStats on every
+// tick.
+type tickInput struct {
+	phase         string
+	elapsed       time.Duration
+	total, errors int64
+	timeoutRate   float64
+	p99           time.Duration
+}
+
+// AlarmStore evaluates AlarmRules on every startProgressTicker tick against a
+// phase's live snapshot, modeled on etcd's alarm package: a rule that crosses
+// its threshold is tracked until it's been continuously breached for Window,
+// then fires exactly once per phase and is dispatched to its Policy.
+type AlarmStore struct {
+	rules []AlarmRule
+
+	mu            sync.Mutex
+	breachedSince map[AlarmType]time.Time
+	fired         []Alarm
+}
+
+// NewAlarmStore builds a store from the given rules (e.g. one per nonzero
+// -alarm-* flag). An empty slice is fine — Evaluate becomes a no-op.
+func NewAlarmStore(rules []AlarmRule) *AlarmStore {
+	return &AlarmStore{
+		rules:         rules,
+		breachedSince: make(map[AlarmType]time.Time),
+	}
+}
+
+// Evaluate checks every rule against in, dispatching any whose threshold has
+// now been continuously crossed for its configured Window. gov and cancel
+// are forwarded to whichever policy a firing rule is dispatched to; either
+// may be nil if the calling phase doesn't support that policy.
+func (a *AlarmStore) Evaluate(in tickInput, gov *rpsGovernor, cancel context.CancelFunc) {
+	errRate := 0.0
+	if in.total > 0 {
+		errRate = float64(in.errors) / float64(in.total)
+	}
+
+	for _, rule := range a.rules {
+		var breached bool
+		var detail string
+		switch rule.Type {
+		case AlarmErrorRate:
+			breached = errRate >= rule.ErrorRateThreshold
+			detail = fmt.Sprintf("error rate %.1f%% >= %.1f%%", errRate*100, rule.ErrorRateThreshold*100)
+		case AlarmLatencySLO:
+			breached = in.p99 >= rule.LatencyThreshold
+			detail = fmt.Sprintf("p99 %s >= %s", fmtDur(in.p99), fmtDur(rule.LatencyThreshold))
+		case AlarmTimeoutStorm:
+			breached = in.timeoutRate >= rule.TimeoutRateThreshold
+			detail = fmt.Sprintf("%.0f timeouts/sec >= %.0f/sec", in.timeoutRate, rule.TimeoutRateThreshold)
+		}
+		a.evaluateRule(rule, breached, detail, in, gov, cancel)
+	}
+}
+
+func (a *AlarmStore) evaluateRule(rule AlarmRule, breached bool, detail string, in tickInput, gov *rpsGovernor, cancel context.CancelFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	since, wasBreached := a.breachedSince[rule.Type]
+	if !breached {
+		if wasBreached {
+			delete(a.breachedSince, rule.Type)
+		}
+		return
+	}
+	if !wasBreached {
+		a.breachedSince[rule.Type] = time.Now()
+		return
+	}
+	if time.Since(since) < rule.Window {
+		return
+	}
+
+	// Rules fire exactly once per phase, like etcd alarms, so a throttled
+	// or warned condition doesn't spam every tick it stays breached.
+	for _, f := range a.fired {
+		if f.Type == rule.Type && f.Phase == in.phase {
+			return
+		}
+	}
+
+	alarm := Alarm{
+		Type:    rule.Type,
+		Policy:  rule.Policy,
+		Phase:   in.phase,
+		FiredAt: in.elapsed,
+		Detail:  fmt.Sprintf("%s for %s", detail, rule.Window),
+	}
+	a.fired = append(a.fired, alarm)
+
+	switch rule.Policy {
+	case PolicyThrottle:
+		if gov != nil {
+			gov.Throttle()
+		}
+		fmt.Printf("  ⚠ ALARM [%s] %s: %s — throttling send rate\n", rule.Type, in.phase, alarm.Detail)
+	case PolicyAbort:
+		fmt.Printf("  ⚠ ALARM [%s] %s: %s — aborting phase\n", rule.Type, in.phase, alarm.Detail)
+		if cancel != nil {
+			cancel()
+		}
+	default: // PolicyWarn
+		fmt.Printf("  ⚠ ALARM [%s] %s: %s\n", rule.Type, in.phase, alarm.Detail)
+	}
+}
+
+// Fired returns every alarm that has fired so far, across all phases.
+func (a *AlarmStore) Fired() []Alarm {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Alarm, len(a.fired))
+	copy(out, a.fired)
+	return out
+}
+
+// rpsGovernor holds a send-rate multiplier (percent, default 100) that
+// rateLimitedSend/rampSend apply to whatever base rate they're already
+// computing, so a PolicyThrottle alarm can scale down the live send rate
+// without the sender goroutine needing to restart.
+type rpsGovernor struct {
+	multiplier atomic.Int64
+}
+
+func newRPSGovernor() *rpsGovernor {
+	g := &rpsGovernor{}
+	g.multiplier.Store(100)
+	return g
+}
+
+// Throttle halves the governor's multiplier, floored at 1%.
+func (g *rpsGovernor) Throttle() {
+	for {
+		cur := g.multiplier.Load()
+		next := cur / 2
+		if next < 1 {
+			next = 1
+		}
+		if g.multiplier.CompareAndSwap(cur, next) {
+			return
+		}
+	}
+}
+
+// Apply scales baseRPS by the governor's current multiplier.
+func (g *rpsGovernor) Apply(baseRPS float64) float64 {
+	return baseRPS * float64(g.multiplier.Load()) / 100
+}
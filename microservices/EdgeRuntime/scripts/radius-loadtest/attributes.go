@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"sync"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+// ─── NAS attribute injection (vendor profiles) ──────────────────────────────
+//
+// doAuth used to send only User-Name + User-Password, which bypasses the
+// code path FreeRADIUS actually exercises against a real access node and
+// makes latency numbers optimistic. AttributePack reproduces the shape of
+// an Access-Request emitted by a real BNG/router — NAS-IP-Address, NAS-Port
+// (-Type), Service-Type, Framed-Protocol, Calling/Called-Station-Id, and a
+// handful of vendor VSAs — selected by -profile.
+
+// NASProfile names a vendor attribute shape AttributePack can build.
+type NASProfile string
+
+const (
+	ProfileNokiaBNG NASProfile = "nokia-bng"
+	ProfileCiscoASR NASProfile = "cisco-asr"
+	ProfileMikrotik NASProfile = "mikrotik"
+)
+
+// parseNASProfile validates a -profile flag value.
+func parseNASProfile(s string) (NASProfile, error) {
+	switch NASProfile(s) {
+	case ProfileNokiaBNG, ProfileCiscoASR, ProfileMikrotik:
+		return NASProfile(s), nil
+	default:
+		return "", fmt.Errorf("unknown profile %q (want %s, %s, or %s)", s, ProfileNokiaBNG, ProfileCiscoASR, ProfileMikrotik)
+	}
+}
+
+// Vendor IDs (IANA Private Enterprise Numbers) for the VSAs below.
+const (
+	vendorNokiaAlcatel = 94
+	vendorCisco        = 9
+	vendorMikrotik     = 14988
+)
+
+// Nokia 7750 SR VSA sub-types — the same vendor-type/vendor-length framing
+// coa.go's vendorProfileName parses on the simulator side of this repo.
+const (
+	nokiaAlcClientHardwareAddr = 42
+	nokiaAlcSAPId              = 184
+	nokiaAlcSubscIDStr         = 247
+)
+
+// Cisco and Mikrotik VSA sub-types used below.
+const (
+	ciscoAVPair       = 1
+	mikrotikRateLimit = 8
+)
+
+// profileNASIP/profileCalledStation are the static per-profile NAS identity
+// and BNG interface this load generator presents on the wire, standing in
+// for the specific chassis an operator under test would configure.
+var profileNASIP = map[NASProfile]net.IP{
+	ProfileNokiaBNG: net.IPv4(10, 20, 30, 1),
+	ProfileCiscoASR: net.IPv4(10, 20, 30, 2),
+	ProfileMikrotik: net.IPv4(10, 20, 30, 3),
+}
+
+var profileCalledStation = map[NASProfile]string{
+	ProfileNokiaBNG: "1/1/1:100",
+	ProfileCiscoASR: "GigabitEthernet0/0/0.100",
+	ProfileMikrotik: "ether1-bng",
+}
+
+// circuit holds the per-user line metadata AttributePack attaches to every
+// Access-Request for that user.
+type circuit struct {
+	MAC     net.HardwareAddr
+	NASPort uint32
+	SAPId   string
+	SubscID string
+}
+
+// deriveCircuit computes stable circuit metadata for username from an FNV
+// hash, so the same username always maps to the same MAC/SAP-Id/Subsc-Id
+// without needing any state persisted between runs.
+func deriveCircuit(username string) circuit {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(username))
+	sum := h.Sum64()
+
+	return circuit{
+		MAC:     net.HardwareAddr{0x00, 0x1e, byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)},
+		NASPort: uint32(sum>>16) % 65536,
+		SAPId:   fmt.Sprintf("1/1/1:%d.%d", 1+(sum>>8)%247, 1+sum%4093),
+		SubscID: fmt.Sprintf("sub-%08x", uint32(sum)),
+	}
+}
+
+// circuitRegistry caches deriveCircuit's result per username so a retried
+// auth within the same run reuses the exact same circuit — FreeRADIUS caches
+// session state keyed on Calling-Station-Id, and a flapping value there
+// would hide the cache-hit path a real deployment relies on.
+type circuitRegistry struct {
+	mu       sync.Mutex
+	circuits map[string]circuit
+}
+
+func newCircuitRegistry() *circuitRegistry {
+	return &circuitRegistry{circuits: make(map[string]circuit)}
+}
+
+func (r *circuitRegistry) forUser(username string) circuit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.circuits[username]; ok {
+		return c
+	}
+	c := deriveCircuit(username)
+	r.circuits[username] = c
+	return c
+}
+
+// AttributePack builds the NAS-side attributes doAuth attaches to every
+// Access-Request, shaped by Profile to match a specific vendor's wire
+// format, with circuit assignments persisted across a run via registry.
+type AttributePack struct {
+	Profile      NASProfile
+	MalformedPct float64
+	registry     *circuitRegistry
+}
+
+// newAttributePack validates cfg.Profile and builds the AttributePack doAuth
+// will consult for every request.
+func newAttributePack(cfg Config) (*AttributePack, error) {
+	profile, err := parseNASProfile(cfg.Profile)
+	if err != nil {
+		return nil, err
+	}
+	return &AttributePack{Profile: profile, MalformedPct: cfg.MalformedPct, registry: newCircuitRegistry()}, nil
+}
+
+// Malformed rolls whether a request should carry a corrupted VSA, per
+// -malformed-pct.
+func (p *AttributePack) Malformed(rng *rand.Rand) bool {
+	return p.MalformedPct > 0 && rng.Float64()*100 < p.MalformedPct
+}
+
+// Apply attaches NAS-IP-Address, NAS-Port(-Type), Service-Type,
+// Framed-Protocol, Calling/Called-Station-Id, and the profile's vendor VSAs
+// to pkt for user. When malformed is true, the profile's first VSA gets a
+// sub-attribute length that overruns its value — garbage a real BNG
+// occasionally emits that operators want FreeRADIUS's behavior measured
+// against.
+func (p *AttributePack) Apply(pkt *radius.Packet, user User, malformed bool) {
+	c := p.registry.forUser(user.Username)
+
+	rfc2865.NASIPAddress_Set(pkt, profileNASIP[p.Profile])
+	rfc2865.NASPort_Set(pkt, rfc2865.NASPort(c.NASPort))
+	rfc2865.NASPortType_Set(pkt, rfc2865.NASPortType_Value_Virtual)
+	rfc2865.ServiceType_Set(pkt, rfc2865.ServiceType_Value_FramedUser)
+	rfc2865.FramedProtocol_Set(pkt, rfc2865.FramedProtocol_Value_PPP)
+	rfc2865.CallingStationID_SetString(pkt, c.MAC.String())
+	rfc2865.CalledStationID_SetString(pkt, profileCalledStation[p.Profile])
+
+	switch p.Profile {
+	case ProfileNokiaBNG:
+		addVSA(pkt, vendorNokiaAlcatel, nokiaAlcClientHardwareAddr, []byte(c.MAC.String()), malformed)
+		addVSA(pkt, vendorNokiaAlcatel, nokiaAlcSAPId, []byte(c.SAPId), false)
+		addVSA(pkt, vendorNokiaAlcatel, nokiaAlcSubscIDStr, []byte(c.SubscID), false)
+	case ProfileCiscoASR:
+		addVSA(pkt, vendorCisco, ciscoAVPair, []byte("subscriber:sub-id="+c.SubscID), malformed)
+	case ProfileMikrotik:
+		addVSA(pkt, vendorMikrotik, mikrotikRateLimit, []byte("10M/10M"), malformed)
+	}
+}
+
+// addVSA appends a Vendor-Specific attribute (RFC 2865 §5.26) carrying one
+// vendor sub-attribute: a 4-byte vendor ID followed by {sub-type,
+// sub-length, value}. When malformed is true, sub-length is inflated past
+// the actual value so FreeRADIUS's dictionary parser sees a sub-attribute
+// claiming more bytes than the packet actually carries.
+func addVSA(pkt *radius.Packet, vendorID uint32, subType byte, value []byte, malformed bool) {
+	vsa := make([]byte, 4+2+len(value))
+	binary.BigEndian.PutUint32(vsa[0:4], vendorID)
+	vsa[4] = subType
+	subLen := len(value) + 2
+	if malformed {
+		subLen += 40 // claims 40 bytes that were never written
+	}
+	vsa[5] = byte(subLen)
+	copy(vsa[6:], value)
+	pkt.Add(radius.Type(26), radius.Attribute(vsa))
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ─── Leveled, structured logging ────────────────────────────────────────────
+//
+// Every phase used to fmt.Printf its banners and the progress ticker's
+// snapshot line straight to stdout, mixed in with the final human report and
+// (once -report json is set) the JSON report — unusable in a pipeline that
+// wants to capture the report and discard the chatter. logger routes all of
+// that through one place instead:
+//
+//   - levels follow telegraf's I!/D!/W!/E! convention
+//   - STLOG=phase,rate,packet enables Debug-level logging for just those
+//     components, the same "global level except these facilities" model as
+//     syncthing's STTRACE, so `-log-level debug` doesn't have to mean every
+//     packet RTT *and* every rate-limiter tick at once
+//   - -log-file routes output to a file, or to journald if set to the literal
+//     value "journald"; empty (the default) goes to stderr, leaving stdout
+//     for the final report and -report output
+//   - -json-logs switches the text "I! [component] message" line for a JSON
+//     object, for log aggregators
+//   - -quiet raises the level to Warn, for CI runs that only want alarms
+
+// Level is a log severity, ordered so higher values are more severe.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// parseLevel maps a -log-level flag value to a Level, defaulting to Info for
+// an unrecognized string rather than erroring — a typo'd level shouldn't
+// crash a load test that's otherwise ready to run.
+func parseLevel(s string) Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug
+	case "warn", "warning":
+		return LevelWarn
+	case "error":
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
+
+// letter is the telegraf-style I!/D!/W!/E! prefix for the text sink.
+func (lvl Level) letter() string {
+	switch lvl {
+	case LevelDebug:
+		return "D!"
+	case LevelWarn:
+		return "W!"
+	case LevelError:
+		return "E!"
+	default:
+		return "I!"
+	}
+}
+
+func (lvl Level) String() string {
+	switch lvl {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// Logger writes leveled, component-tagged lines to a single sink, either as
+// telegraf-style text or as JSON. Safe for concurrent use by every phase's
+// goroutines.
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  Level
+	json   bool
+	traced map[string]bool // components STLOG names; Debug logs for these pass regardless of level
+}
+
+// newLogger builds a Logger. stlog is the raw STLOG env value (comma
+// separated component names; empty disables the override).
+func newLogger(out io.Writer, level Level, jsonOut bool, stlog string) *Logger {
+	l := &Logger{out: out, level: level, json: jsonOut}
+	if stlog != "" {
+		l.traced = make(map[string]bool)
+		for _, c := range strings.Split(stlog, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				l.traced[c] = true
+			}
+		}
+	}
+	return l
+}
+
+// enabled reports whether a log at lvl for component should be written:
+// either the global level admits it, or STLOG named component explicitly and
+// lvl is Debug.
+func (l *Logger) enabled(component string, lvl Level) bool {
+	if lvl >= l.level {
+		return true
+	}
+	return lvl == LevelDebug && l.traced[component]
+}
+
+// DebugEnabled reports whether a Debugf(component, ...) call would actually
+// be written. doAuth's per-packet logging sits in the hottest path this tool
+// has, so it guards its Debugf calls with this rather than letting logf's
+// own (otherwise sufficient) enabled() check happen after the variadic args
+// are already boxed — at default -log-level info, that boxing would run on
+// every single auth exchange for a line nobody sees.
+func (l *Logger) DebugEnabled(component string) bool {
+	return l.enabled(component, LevelDebug)
+}
+
+func (l *Logger) logf(lvl Level, component, format string, args ...interface{}) {
+	if !l.enabled(component, lvl) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	now := time.Now().UTC()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var err error
+	if l.json {
+		var line []byte
+		line, err = json.Marshal(struct {
+			Time      string `json:"time"`
+			Level     string `json:"level"`
+			Component string `json:"component"`
+			Msg       string `json:"msg"`
+		}{now.Format(time.RFC3339Nano), lvl.String(), component, msg})
+		if err == nil {
+			_, err = l.out.Write(append(line, '\n'))
+		}
+	} else {
+		_, err = fmt.Fprintf(l.out, "%s %s [%s] %s\n", now.Format(time.RFC3339), lvl.letter(), component, msg)
+	}
+
+	// A sink that starts failing mid-run (e.g. journald restarting under a
+	// multi-hour SoakPhase) falls back to stderr instead of silently
+	// dropping every log line for the rest of the run.
+	if err != nil && l.out != os.Stderr {
+		fmt.Fprintf(os.Stderr, "[warn] log sink failed, falling back to stderr: %v\n", err)
+		l.out = os.Stderr
+	}
+}
+
+func (l *Logger) Debugf(component, format string, args ...interface{}) {
+	l.logf(LevelDebug, component, format, args...)
+}
+func (l *Logger) Infof(component, format string, args ...interface{}) {
+	l.logf(LevelInfo, component, format, args...)
+}
+func (l *Logger) Warnf(component, format string, args ...interface{}) {
+	l.logf(LevelWarn, component, format, args...)
+}
+func (l *Logger) Errorf(component, format string, args ...interface{}) {
+	l.logf(LevelError, component, format, args...)
+}
+
+// log is the process-wide Logger, defaulting to text-on-stderr at Info so
+// every file can log before main() has parsed flags; main() replaces it with
+// one built from -log-level/-log-file/-json-logs/-quiet and STLOG.
+var log = newLogger(os.Stderr, LevelInfo, false, os.Getenv("STLOG"))
+
+// newLoggerFromFlags builds the real process logger once flags are parsed,
+// opening logFile (or dialing journald, for the literal value "journald")
+// for its sink. A file/journald open failure falls back to stderr rather
+// than aborting the run over a logging misconfiguration.
+func newLoggerFromFlags(level string, logFile string, jsonLogs, quiet bool) *Logger {
+	lvl := parseLevel(level)
+	if quiet && lvl < LevelWarn {
+		lvl = LevelWarn
+	}
+
+	out := io.Writer(os.Stderr)
+	switch logFile {
+	case "":
+	case "journald":
+		if w, err := newJournaldWriter(); err != nil {
+			fmt.Fprintf(os.Stderr, "[warn] journald sink unavailable, falling back to stderr: %v\n", err)
+		} else {
+			out = w
+		}
+	default:
+		f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[warn] log file %s unavailable, falling back to stderr: %v\n", logFile, err)
+		} else {
+			out = f
+		}
+	}
+
+	return newLogger(out, lvl, jsonLogs, os.Getenv("STLOG"))
+}
+
+// journaldWriter sends each Write as one journald native-protocol datagram
+// over /run/systemd/journal/socket, avoiding a cgo/systemd client dependency
+// for what's otherwise a single MESSAGE=/PRIORITY= key-value pair per line.
+type journaldWriter struct {
+	conn net.Conn
+}
+
+func newJournaldWriter() (*journaldWriter, error) {
+	conn, err := net.Dial("unixgram", "/run/systemd/journal/socket")
+	if err != nil {
+		return nil, err
+	}
+	return &journaldWriter{conn: conn}, nil
+}
+
+// Write sends p (one already-formatted log line) to journald at PRIORITY=6
+// (info); journald doesn't need our text's own level letter since that's
+// carried in the message body, and per-level PRIORITY mapping isn't worth
+// the native protocol's binary-field escaping for a load test's log volume.
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	datagram := fmt.Sprintf("PRIORITY=6\nMESSAGE=%s\n", msg)
+	if _, err := w.conn.Write([]byte(datagram)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
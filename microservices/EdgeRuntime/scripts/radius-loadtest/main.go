@@ -3,13 +3,16 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"math"
 	"math/rand"
+	"net"
 	"os"
 	"os/signal"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -42,6 +45,15 @@ import (
 //  Phase 4: SUSTAINED PEAK — Max throughput test
 //     Continuous auth at peak rate for 60s to find sustained capacity
 //
+//  Phase 5: CoA/DM BURST — Operator bounce/reauthorize traffic
+//     CoA-Request/Disconnect-Message packets against a NAS (or a built-in
+//     mock NAS for isolation testing), per RFC 3576/5176
+//
+//  Accounting (-acct): every Access-Accept across phases 1-4 also spawns a
+//  goroutine simulating that session's RFC 2866 Start/Interim-Update/Stop
+//  lifecycle, tracked separately in the final report since it hits a
+//  different FreeRADIUS code path than auth.
+//
 // ═══════════════════════════════════════════════════════════════════════════
 
 type User struct {
@@ -54,6 +66,7 @@ type Result struct {
 	Success bool
 	Reject  bool
 	Error   bool
+	Timeout bool // true when Error is a context/connection timeout, not a reject
 }
 
 type Config struct {
@@ -75,21 +88,142 @@ type Config struct {
 	SteadyRPS   int // auth/sec during steady state
 	PeakRPS     int // target auth/sec at peak
 	OutageBatch int // users per second during outage recovery
+
+	// SLO alarms (see AlarmStore); a threshold of 0 disables that rule.
+	AlarmErrorRate    float64       // fraction of requests, e.g. 0.05 for 5%
+	AlarmP99          time.Duration // p99 ceiling
+	AlarmTimeoutsRate float64       // timeouts/sec
+	AlarmWindow       time.Duration // how long a threshold must stay breached before it fires
+	AlarmPolicy       string        // warn, throttle, or abort
+
+	// Live metrics fork-out (see metrics.go); empty disables each.
+	MetricsAddr string
+	PushInflux  string
+
+	// Host resource sampling (see resources.go): which NIC to pull packet/
+	// byte counters from while a phase runs. Empty sums every non-loopback
+	// interface, since the generator's egress path usually isn't known.
+	Iface string
+
+	// Accounting session lifecycle (RFC 2866, see session.go); each
+	// Access-Accept spawns a goroutine sending Start, periodic
+	// Interim-Updates, and a final Stop.
+	Acct                bool
+	AcctPort            int
+	AcctInterimInterval time.Duration
+
+	// CoA/Disconnect-Message burst (RFC 3576/5176, see session.go).
+	CoAListen   string
+	CoATarget   string
+	CoARPS      int
+	CoADuration time.Duration
+
+	// NAS attribute injection (see attributes.go): which vendor's Access-
+	// Request shape doAuth reproduces, and what fraction should carry a
+	// deliberately corrupted VSA.
+	Profile      string
+	MalformedPct float64
+
+	// Adaptive AIMD rate control (see aimd.go); when Adaptive is set,
+	// Phase 2 (RAMP UP) searches for max sustainable RPS against these SLOs
+	// instead of running a fixed linear ramp.
+	Adaptive     bool
+	SLOP95       time.Duration
+	SLOErrorRate float64
+	AIMDIncrease float64
+
+	// Machine-readable phase reports (see report.go), selected by the
+	// LOADTEST_REPORT/LOADTEST_REPORT_DIR env vars rather than flags, same
+	// as the LOADTEST_METRICS/LOADTEST_PPROF debug toggles in metrics.go.
+	ReportFormats []string
+	ReportDir     string
+
+	// Per-phase SLOs a nightly CI job gates regressions on (see report.go's
+	// evaluatePhase); a threshold of 0 disables that check.
+	MaxRejectRate      float64
+	MaxP99             time.Duration
+	SteadySLO          time.Duration
+	PeakSLO            time.Duration
+	OutageRecoveryTime time.Duration
+
+	// Scenario engine (see phase.go/scenario.go): a YAML file listing phases
+	// to run instead of the built-in STEADY STATE/RAMP UP/POWER OUTAGE/
+	// SUSTAINED PEAK story. Empty runs defaultPhases(cfg).
+	Scenario string
+
+	// Logging (see logger.go): level/sink/format for the phase banners and
+	// progress ticker that used to be unconditional fmt.Printf calls, plus
+	// -quiet for CI runs that only want warnings and above.
+	LogLevel string
+	LogFile  string
+	JSONLogs bool
+	Quiet    bool
 }
 
 // ─── Live metrics (lock-free) ───────────────────────────────────────────────
 
+// histMinNanos/histMaxNanos/histNumBuckets fix a log-spaced latency
+// histogram covering ~10µs to ~60s at roughly 2% resolution per bucket
+// (~1.02x growth per step). They're compile-time
+// constants rather than something derived from observed samples so the
+// bucket boundaries are identical across runs and a CI job can diff two
+// PrintHistogram outputs bucket-for-bucket.
+const (
+	histMinNanos   = int64(10 * time.Microsecond)
+	histMaxNanos   = int64(60 * time.Second)
+	histNumBuckets = 800
+
+	// histDisplayRows is how many rows PrintHistogram prints, merging
+	// adjacent histNumBuckets buckets so the output stays readable.
+	histDisplayRows = 20
+)
+
+// histBucketBounds holds the upper edge (in nanoseconds) of each histogram
+// bucket, computed once at package init so Record never allocates.
+var histBucketBounds = buildHistBucketBounds()
+
+func buildHistBucketBounds() [histNumBuckets]int64 {
+	var bounds [histNumBuckets]int64
+	growth := math.Pow(float64(histMaxNanos)/float64(histMinNanos), 1/float64(histNumBuckets-1))
+	edge := float64(histMinNanos)
+	for i := range bounds {
+		bounds[i] = int64(edge)
+		edge *= growth
+	}
+	bounds[histNumBuckets-1] = histMaxNanos
+	return bounds
+}
+
+// bucketFor returns the histBucketBounds index of the smallest bucket whose
+// upper edge is >= nanos.
+func bucketFor(nanos int64) int {
+	idx := sort.Search(histNumBuckets, func(i int) bool { return histBucketBounds[i] >= nanos })
+	if idx >= histNumBuckets {
+		idx = histNumBuckets - 1
+	}
+	return idx
+}
+
 type LiveStats struct {
-	Sent   atomic.Int64
-	Accept atomic.Int64
-	Reject atomic.Int64
-	Error  atomic.Int64
-	LatSum atomic.Int64 // microseconds
-	LatMax atomic.Int64
-
-	// Per-second sliding window
-	mu         sync.Mutex
-	secResults []Result
+	Sent     atomic.Int64
+	Accept   atomic.Int64
+	Reject   atomic.Int64
+	Error    atomic.Int64
+	Timeouts atomic.Int64 // subset of Error that timed out rather than being rejected
+	LatSum   atomic.Int64 // microseconds
+	LatMax   atomic.Int64
+	Inflight atomic.Int64 // requests currently in flight for this phase
+
+	// buckets is a fixed-memory latency histogram: one lock-free counter
+	// per histBucketBounds entry. It replaces a mutex-guarded slice of
+	// every Result ever recorded, so Record stays O(1) and allocation-free
+	// no matter how long a phase runs, and Percentiles/PrintHistogram read
+	// it by walking cumulative counts instead of sorting raw samples.
+	buckets [histNumBuckets]atomic.Int64
+
+	// Phase labels every Result this LiveStats records when forking them
+	// out to Prometheus/Influx (see metrics.go); set once at construction.
+	Phase string
 }
 
 func (ls *LiveStats) Record(r Result) {
@@ -99,6 +233,9 @@ func (ls *LiveStats) Record(r Result) {
 
 	if r.Error {
 		ls.Error.Add(1)
+		if r.Timeout {
+			ls.Timeouts.Add(1)
+		}
 	} else if r.Success {
 		ls.Accept.Add(1)
 	} else {
@@ -113,9 +250,22 @@ func (ls *LiveStats) Record(r Result) {
 		}
 	}
 
-	ls.mu.Lock()
-	ls.secResults = append(ls.secResults, r)
-	ls.mu.Unlock()
+	ls.buckets[bucketFor(r.Latency.Nanoseconds())].Add(1)
+
+	metrics.RequestsTotal.WithLabelValues(resultLabel(r), ls.Phase).Inc()
+	metrics.LatencySeconds.WithLabelValues(ls.Phase).Observe(r.Latency.Seconds())
+}
+
+// resultLabel is the "result" label value Record reports a Result under.
+func resultLabel(r Result) string {
+	switch {
+	case r.Error:
+		return "error"
+	case r.Reject:
+		return "reject"
+	default:
+		return "accept"
+	}
 }
 
 func (ls *LiveStats) Snapshot() (total, accept, reject, errors int64, avgMs, maxMs float64) {
@@ -130,40 +280,108 @@ func (ls *LiveStats) Snapshot() (total, accept, reject, errors int64, avgMs, max
 	return
 }
 
+// snapshotBuckets reads every bucket counter once, returning the per-bucket
+// counts alongside their sum.
+func (ls *LiveStats) snapshotBuckets() (counts [histNumBuckets]int64, total int64) {
+	for i := range ls.buckets {
+		c := ls.buckets[i].Load()
+		counts[i] = c
+		total += c
+	}
+	return
+}
+
 func (ls *LiveStats) Percentiles() (p50, p95, p99 time.Duration) {
-	ls.mu.Lock()
-	results := make([]Result, len(ls.secResults))
-	copy(results, ls.secResults)
-	ls.mu.Unlock()
+	counts, total := ls.snapshotBuckets()
+	p50 = pctile(counts, total, 50)
+	p95 = pctile(counts, total, 95)
+	p99 = pctile(counts, total, 99)
+	return
+}
 
-	if len(results) == 0 {
-		return
+// pctile walks the histogram's cumulative bucket counts to find the bucket
+// crossing the target rank, then linearly interpolates within that bucket's
+// lower/upper bound range under the assumption samples are spread uniformly
+// across it.
+func pctile(counts [histNumBuckets]int64, total int64, pct float64) time.Duration {
+	if total == 0 {
+		return 0
 	}
-
-	lats := make([]time.Duration, len(results))
-	for i, r := range results {
-		lats[i] = r.Latency
+	target := int64(math.Ceil(pct / 100 * float64(total)))
+	if target < 1 {
+		target = 1
 	}
-	sort.Slice(lats, func(i, j int) bool { return lats[i] < lats[j] })
 
-	p50 = pctile(lats, 50)
-	p95 = pctile(lats, 95)
-	p99 = pctile(lats, 99)
-	return
+	lower := histMinNanos
+	var cumulative int64
+	for i, count := range counts {
+		if cumulative+count >= target {
+			if count == 0 {
+				return time.Duration(histBucketBounds[i])
+			}
+			frac := float64(target-cumulative) / float64(count)
+			nanos := float64(lower) + frac*float64(histBucketBounds[i]-lower)
+			return time.Duration(nanos)
+		}
+		cumulative += count
+		lower = histBucketBounds[i]
+	}
+	return time.Duration(histMaxNanos)
 }
 
-func pctile(sorted []time.Duration, pct float64) time.Duration {
-	if len(sorted) == 0 {
-		return 0
+// PrintHistogram prints a boom/hey-style bar-chart distribution of recorded
+// latencies, merging the underlying log-spaced buckets into histDisplayRows
+// rows. Bucket boundaries are fixed at compile time (histMinNanos/
+// histMaxNanos/histNumBuckets), so two runs' histograms line up row-for-row
+// and a CI job can diff them directly.
+func (ls *LiveStats) PrintHistogram() {
+	counts, total := ls.snapshotBuckets()
+	if total == 0 {
+		return
+	}
+
+	fmt.Printf("  Latency distribution (%d buckets, %s-%s log-spaced):\n",
+		histNumBuckets, fmtDur(time.Duration(histMinNanos)), fmtDur(time.Duration(histMaxNanos)))
+
+	rowsPerBucket := histNumBuckets / histDisplayRows
+	var rowCounts [histDisplayRows]int64
+	for i, c := range counts {
+		row := i / rowsPerBucket
+		if row >= histDisplayRows {
+			row = histDisplayRows - 1
+		}
+		rowCounts[row] += c
 	}
-	idx := int(math.Ceil(pct/100*float64(len(sorted)))) - 1
-	if idx < 0 {
-		idx = 0
+
+	var maxRow int64
+	for _, c := range rowCounts {
+		if c > maxRow {
+			maxRow = c
+		}
 	}
-	if idx >= len(sorted) {
-		idx = len(sorted) - 1
+
+	var cumulative int64
+	for row, c := range rowCounts {
+		cumulative += c
+		upperIdx := (row+1)*rowsPerBucket - 1
+		if upperIdx >= histNumBuckets {
+			upperIdx = histNumBuckets - 1
+		}
+		upper := histBucketBounds[upperIdx]
+		cumPct := float64(cumulative) / float64(total) * 100
+		fmt.Printf("    %8s [%6d]\t%s  (%.1f%% cum)\n",
+			fmtDur(time.Duration(upper)), c, histBar(c, maxRow), cumPct)
+	}
+}
+
+// histBar renders count as a block-character bar scaled against max,
+// hey/boom style.
+func histBar(count, max int64) string {
+	if max == 0 {
+		return ""
 	}
-	return sorted[idx]
+	const width = 40
+	return strings.Repeat("■", int(count*width/max))
 }
 
 // ─── Load users from PostgreSQL ─────────────────────────────────────────────
@@ -287,17 +505,24 @@ func cleanupSyntheticUsers(dsn string) {
 
 // ─── RADIUS auth request ────────────────────────────────────────────────────
 
-func doAuth(ctx context.Context, addr string, secret []byte, user User) Result {
+func doAuth(ctx context.Context, addr string, secret []byte, user User, pack *AttributePack, malformed bool) Result {
 	pkt := radius.New(radius.CodeAccessRequest, secret)
 	rfc2865.UserName_SetString(pkt, user.Username)
 	rfc2865.UserPassword_SetString(pkt, user.Password)
+	pack.Apply(pkt, user, malformed)
 
 	start := time.Now()
 	resp, err := radius.Exchange(ctx, pkt, addr)
 	lat := time.Since(start)
 
 	if err != nil {
-		return Result{Latency: lat, Error: true}
+		if log.DebugEnabled("packet") {
+			log.Debugf("packet", "id=%d code=%s rtt=%s error=%v", pkt.Identifier, pkt.Code, lat, err)
+		}
+		return Result{Latency: lat, Error: true, Timeout: isTimeoutErr(err)}
+	}
+	if log.DebugEnabled("packet") {
+		log.Debugf("packet", "id=%d code=%s rtt=%s", resp.Identifier, resp.Code, lat)
 	}
 	return Result{
 		Latency: lat,
@@ -306,12 +531,23 @@ func doAuth(ctx context.Context, addr string, secret []byte, user User) Result {
 	}
 }
 
+// isTimeoutErr reports whether err represents a request timing out rather
+// than some other transport failure, so LiveStats can track timeouts
+// separately for TIMEOUT_STORM alarms.
+func isTimeoutErr(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // ─── Rate-limited sender ────────────────────────────────────────────────────
 // Sends auth requests at a given rate (req/sec), picking random users.
 // Returns when ctx is cancelled or duration expires.
 
-func rateLimitedSend(ctx context.Context, cfg Config, users []User, stats *LiveStats,
-	rps int, duration time.Duration, concurrency int) {
+func rateLimitedSend(ctx context.Context, cfg Config, users []User, stats, acctStats *LiveStats,
+	rps int, duration time.Duration, concurrency int, gov *rpsGovernor, pack *AttributePack) {
 
 	if rps <= 0 || duration <= 0 {
 		return
@@ -322,9 +558,11 @@ func rateLimitedSend(ctx context.Context, cfg Config, users []User, stats *LiveS
 	sem := make(chan struct{}, concurrency)
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	interval := time.Second / time.Duration(rps)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	// A timer re-armed with gov's current rate each send, rather than a
+	// fixed ticker, so an AlarmStore throttle policy takes effect on the
+	// very next send instead of requiring a restart.
+	timer := time.NewTimer(time.Second / time.Duration(rps))
+	defer timer.Stop()
 
 	deadline := time.After(duration)
 	var wg sync.WaitGroup
@@ -337,32 +575,50 @@ func rateLimitedSend(ctx context.Context, cfg Config, users []User, stats *LiveS
 		case <-deadline:
 			wg.Wait()
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			user := users[rng.Intn(len(users))]
+			malformed := pack.Malformed(rng)
 			sem <- struct{}{}
 			wg.Add(1)
-			go func(u User) {
+			metrics.Inflight.Inc()
+			stats.Inflight.Add(1)
+			go func(u User, malformed bool) {
 				defer wg.Done()
 				defer func() { <-sem }()
+				defer metrics.Inflight.Dec()
+				defer stats.Inflight.Add(-1)
 				reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
 				defer cancel()
-				r := doAuth(reqCtx, addr, secret, u)
+				r := doAuth(reqCtx, addr, secret, u, pack, malformed)
 				stats.Record(r)
-			}(user)
+				if cfg.Acct && r.Success {
+					go runAccountingSession(ctx, cfg, u, acctStats)
+				}
+			}(user, malformed)
+
+			currentRPS := gov.Apply(float64(rps))
+			if currentRPS < 1 {
+				currentRPS = 1
+			}
+			timer.Reset(time.Second / time.Duration(currentRPS))
 		}
 	}
 }
 
 // ─── Ramp sender: linearly increase RPS over duration ───────────────────────
 
-func rampSend(ctx context.Context, cfg Config, users []User, stats *LiveStats,
-	startRPS, endRPS int, duration time.Duration, concurrency int) {
+func rampSend(ctx context.Context, cfg Config, users []User, stats, acctStats *LiveStats,
+	startRPS, endRPS int, duration time.Duration, concurrency int, gov *rpsGovernor, pack *AttributePack, aimd *aimdController) {
 
 	addr := fmt.Sprintf("%s:%d", cfg.RadiusHost, cfg.RadiusPort)
 	secret := []byte(cfg.RadiusSecret)
 	sem := make(chan struct{}, concurrency)
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
+	if aimd != nil {
+		go runAIMD(ctx, aimd, stats)
+	}
+
 	start := time.Now()
 	var wg sync.WaitGroup
 
@@ -379,9 +635,16 @@ func rampSend(ctx context.Context, cfg Config, users []User, stats *LiveStats,
 		default:
 		}
 
-		// Linear interpolation of current RPS
-		progress := float64(elapsed) / float64(duration)
-		currentRPS := float64(startRPS) + progress*float64(endRPS-startRPS)
+		// aimd, when set, drives the target RPS instead of the linear
+		// interpolation below (see -adaptive); either way gov still applies
+		// in case an AlarmStore throttle policy has also fired.
+		var currentRPS float64
+		if aimd != nil {
+			currentRPS = gov.Apply(aimd.Target())
+		} else {
+			progress := float64(elapsed) / float64(duration)
+			currentRPS = gov.Apply(float64(startRPS) + progress*float64(endRPS-startRPS))
+		}
 		if currentRPS < 1 {
 			currentRPS = 1
 		}
@@ -390,16 +653,24 @@ func rampSend(ctx context.Context, cfg Config, users []User, stats *LiveStats,
 		time.Sleep(interval)
 
 		user := users[rng.Intn(len(users))]
+		malformed := pack.Malformed(rng)
 		sem <- struct{}{}
 		wg.Add(1)
-		go func(u User) {
+		metrics.Inflight.Inc()
+		stats.Inflight.Add(1)
+		go func(u User, malformed bool) {
 			defer wg.Done()
 			defer func() { <-sem }()
+			defer metrics.Inflight.Dec()
+			defer stats.Inflight.Add(-1)
 			reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
 			defer cancel()
-			r := doAuth(reqCtx, addr, secret, u)
+			r := doAuth(reqCtx, addr, secret, u, pack, malformed)
 			stats.Record(r)
-		}(user)
+			if cfg.Acct && r.Success {
+				go runAccountingSession(ctx, cfg, u, acctStats)
+			}
+		}(user, malformed)
 	}
 
 	wg.Wait()
@@ -409,8 +680,8 @@ func rampSend(ctx context.Context, cfg Config, users []User, stats *LiveStats,
 // Simulates all CPEs rebooting after power restore.
 // Modems boot in ~30-120s with jitter, so auth requests arrive in waves.
 
-func outageBurst(ctx context.Context, cfg Config, users []User, stats *LiveStats,
-	duration time.Duration, concurrency int) {
+func outageBurst(ctx context.Context, cfg Config, users []User, stats, acctStats *LiveStats,
+	duration time.Duration, concurrency int, pack *AttributePack) {
 
 	addr := fmt.Sprintf("%s:%d", cfg.RadiusHost, cfg.RadiusPort)
 	secret := []byte(cfg.RadiusSecret)
@@ -473,16 +744,24 @@ func outageBurst(ctx context.Context, cfg Config, users []User, stats *LiveStats
 		// Fire all users whose boot time has arrived
 		for idx < len(schedule) && schedule[idx].delay <= elapsed {
 			user := schedule[idx].user
+			malformed := pack.Malformed(rng)
 			sem <- struct{}{}
 			wg.Add(1)
-			go func(u User) {
+			metrics.Inflight.Inc()
+			stats.Inflight.Add(1)
+			go func(u User, malformed bool) {
 				defer wg.Done()
 				defer func() { <-sem }()
+				defer metrics.Inflight.Dec()
+				defer stats.Inflight.Add(-1)
 				reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
 				defer cancel()
-				r := doAuth(reqCtx, addr, secret, u)
+				r := doAuth(reqCtx, addr, secret, u, pack, malformed)
 				stats.Record(r)
-			}(user)
+				if cfg.Acct && r.Success {
+					go runAccountingSession(ctx, cfg, u, acctStats)
+				}
+			}(user, malformed)
 			idx++
 		}
 
@@ -495,12 +774,20 @@ func outageBurst(ctx context.Context, cfg Config, users []User, stats *LiveStats
 
 // ─── Live progress ticker ───────────────────────────────────────────────────
 
-func startProgressTicker(ctx context.Context, stats *LiveStats, phase string) {
+// startProgressTicker logs a one-line snapshot every 2s at Debug level under
+// the "rate" component (shown with -log-level debug or STLOG=rate), updates
+// the per-phase Prometheus gauges, and, when alarms is
+// non-nil, evaluates its rules against that same snapshot. tag is the short
+// label used in the printed line; phaseName is the full PhaseSummary name an
+// alarm should be attributed to. gov and cancel are forwarded to whichever
+// policy a firing rule dispatches to (throttle / abort respectively) and may
+// be nil if this phase doesn't support that policy.
+func startProgressTicker(ctx context.Context, stats *LiveStats, tag, phaseName string, alarms *AlarmStore, gov *rpsGovernor, cancel context.CancelFunc) {
 	go func() {
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
 		start := time.Now()
-		var lastSent int64
+		var lastSent, lastTimeouts int64
 
 		for {
 			select {
@@ -512,16 +799,40 @@ func startProgressTicker(ctx context.Context, stats *LiveStats, phase string) {
 				delta := total - lastSent
 				lastSent = total
 				instantRPS := float64(delta) / 2.0
+				metrics.CurrentRPS.Set(instantRPS)
+
+				timeouts := stats.Timeouts.Load()
+				timeoutRate := float64(timeouts-lastTimeouts) / 2.0
+				lastTimeouts = timeouts
+
+				phaseTag := strings.TrimSpace(tag)
+				metrics.PhaseRPS.WithLabelValues(phaseTag).Set(instantRPS)
+				metrics.PhaseAccepts.WithLabelValues(phaseTag).Set(float64(accept))
+				metrics.PhaseRejects.WithLabelValues(phaseTag).Set(float64(reject))
+				metrics.PhaseTimeouts.WithLabelValues(phaseTag).Set(float64(timeouts))
+				metrics.PhaseInflight.WithLabelValues(phaseTag).Set(float64(stats.Inflight.Load()))
 
 				errPct := 0.0
 				if total > 0 {
 					errPct = float64(errors) / float64(total) * 100
 				}
 
-				fmt.Printf("  [%s +%s] %d sent (%.0f/s) │ ✓%d ✗%d ⚠%d (%.1f%%) │ avg=%.1fms max=%.1fms\n",
-					phase, elapsed, total, instantRPS,
+				log.Debugf("rate", "[%s +%s] %d sent (%.0f/s) | accept=%d reject=%d error=%d (%.1f%%) | avg=%.1fms max=%.1fms",
+					tag, elapsed, total, instantRPS,
 					accept, reject, errors, errPct,
 					avgMs, maxMs)
+
+				if alarms != nil {
+					_, _, p99 := stats.Percentiles()
+					alarms.Evaluate(tickInput{
+						phase:       phaseName,
+						elapsed:     elapsed,
+						total:       total,
+						errors:      errors,
+						timeoutRate: timeoutRate,
+						p99:         p99,
+					}, gov, cancel)
+				}
 			}
 		}
 	}()
@@ -539,7 +850,7 @@ func fmtDur(d time.Duration) string {
 	return fmt.Sprintf("%.2fs", d.Seconds())
 }
 
-func printPhaseResult(name string, stats *LiveStats, dur time.Duration) {
+func printPhaseResult(name string, stats *LiveStats, dur time.Duration, res *ResourceReport) {
 	total, accept, reject, errors, avgMs, maxMs := stats.Snapshot()
 	p50, p95, p99 := stats.Percentiles()
 
@@ -561,39 +872,67 @@ func printPhaseResult(name string, stats *LiveStats, dur time.Duration) {
 	fmt.Printf("│  Latency:    avg=%.1fms  max=%.1fms\n", avgMs, maxMs)
 	fmt.Printf("│  Percentile: p50=%s  p95=%s  p99=%s\n", fmtDur(p50), fmtDur(p95), fmtDur(p99))
 	fmt.Printf("│  Throughput: %.1f req/sec\n", rps)
+	if res != nil {
+		fmt.Printf("│  Resources:  cpu avg=%.1f%% peak=%.1f%%  load1 peak=%.2f  rss peak=%.0fMB  pps sent avg=%.0f/s peak=%.0f/s\n",
+			res.CPUAvgPct, res.CPUPeakPct, res.Load1Peak, res.RSSPeakMB, res.PPSSentAvg, res.PPSSentPeak)
+		if res.Starved {
+			fmt.Printf("│  ⚠ RESOURCE-STARVED — generator saturated itself; treat this phase's numbers as unreliable\n")
+			log.Warnf("resource", "generator CPU %.1f%% peak during %s — results suspect", res.CPUPeakPct, name)
+		}
+	}
 	fmt.Printf("└──────────────────────────────────────────────────────────────────\n")
 }
 
 // ─── Phase summary for final report ─────────────────────────────────────────
 
 type PhaseSummary struct {
-	Name     string
-	Duration time.Duration
-	Total    int64
-	Accept   int64
-	Reject   int64
-	Errors   int64
-	AvgMs    float64
-	MaxMs    float64
-	P50      time.Duration
-	P95      time.Duration
-	P99      time.Duration
-	RPS      float64
+	Name      string
+	Duration  time.Duration
+	Total     int64
+	Accept    int64
+	Reject    int64
+	Errors    int64
+	AvgMs     float64
+	MaxMs     float64
+	P50       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+	RPS       float64
+	Stats     *LiveStats      // retained so the final report can print its histogram
+	Alarms    []Alarm         // SLO alarms that fired during this phase, if any
+	AIMD      *AIMDReport     // -adaptive RAMP UP's rate trajectory, nil otherwise
+	Resources *ResourceReport // host resource footprint sampled during this phase, nil if unavailable
 }
 
-func capturePhase(name string, stats *LiveStats, dur time.Duration) PhaseSummary {
+func capturePhase(name string, stats *LiveStats, dur time.Duration, alarms *AlarmStore, res *resourceSampler) PhaseSummary {
 	total, accept, reject, errors, avgMs, maxMs := stats.Snapshot()
 	p50, p95, p99 := stats.Percentiles()
 	rps := 0.0
 	if dur.Seconds() > 0 {
 		rps = float64(total) / dur.Seconds()
 	}
-	return PhaseSummary{
+
+	var phaseAlarms []Alarm
+	if alarms != nil {
+		for _, a := range alarms.Fired() {
+			if a.Phase == name {
+				phaseAlarms = append(phaseAlarms, a)
+			}
+		}
+	}
+
+	summary := PhaseSummary{
 		Name: name, Duration: dur,
 		Total: total, Accept: accept, Reject: reject, Errors: errors,
 		AvgMs: avgMs, MaxMs: maxMs,
 		P50: p50, P95: p95, P99: p99, RPS: rps,
+		Stats: stats, Alarms: phaseAlarms,
 	}
+	if res != nil {
+		r := res.Report()
+		summary.Resources = &r
+	}
+	return summary
 }
 
 func printFinalReport(phases []PhaseSummary, userCount int) {
@@ -620,6 +959,21 @@ func printFinalReport(phases []PhaseSummary, userCount int) {
 			p.Name, p.Total, p.RPS, errPct,
 			fmtDur(time.Duration(p.AvgMs*1000)*time.Microsecond),
 			fmtDur(p.P50), fmtDur(p.P95), fmtDur(p.P99))
+		for _, a := range p.Alarms {
+			fmt.Printf("    ⚠ [%s/%s] %s at +%s: %s\n",
+				a.Type, a.Policy, p.Name, a.FiredAt.Round(time.Second), a.Detail)
+		}
+		if p.AIMD != nil {
+			fmt.Printf("    📈 AIMD: max sustainable ~%.0f req/sec, %d backoff(s)\n", p.AIMD.MaxRPS, p.AIMD.Backoffs)
+		}
+		if p.Resources != nil {
+			r := p.Resources
+			fmt.Printf("    🖥️  cpu avg=%.1f%% peak=%.1f%%  load1 peak=%.2f  rss peak=%.0fMB  pps sent avg=%.0f/s peak=%.0f/s\n",
+				r.CPUAvgPct, r.CPUPeakPct, r.Load1Peak, r.RSSPeakMB, r.PPSSentAvg, r.PPSSentPeak)
+			if r.Starved {
+				fmt.Printf("    ⚠ RESOURCE-STARVED — generator saturated itself during %s; treat its numbers as unreliable\n", p.Name)
+			}
+		}
 
 		grandTotal += p.Total
 		grandAccept += p.Accept
@@ -649,6 +1003,7 @@ func printFinalReport(phases []PhaseSummary, userCount int) {
 	// Check outage phase specifically (most demanding)
 	var outagePhase *PhaseSummary
 	var peakPhase *PhaseSummary
+	var rampPhase *PhaseSummary
 	for i := range phases {
 		if phases[i].Name == "POWER OUTAGE" {
 			outagePhase = &phases[i]
@@ -656,6 +1011,9 @@ func printFinalReport(phases []PhaseSummary, userCount int) {
 		if phases[i].Name == "SUSTAINED PEAK" {
 			peakPhase = &phases[i]
 		}
+		if phases[i].Name == "RAMP UP" {
+			rampPhase = &phases[i]
+		}
 	}
 
 	fmt.Println("  ┌─ VERDICT ─────────────────────────────────────────────────────")
@@ -707,6 +1065,20 @@ func printFinalReport(phases []PhaseSummary, userCount int) {
 
 	fmt.Println("  └────────────────────────────────────────────────────────────────")
 	fmt.Println()
+
+	if peakPhase != nil && peakPhase.Stats != nil {
+		fmt.Println("  ┌─ SUSTAINED PEAK LATENCY DISTRIBUTION ─────────────────────────")
+		peakPhase.Stats.PrintHistogram()
+		fmt.Println("  └────────────────────────────────────────────────────────────────")
+		fmt.Println()
+	}
+
+	if rampPhase != nil && rampPhase.AIMD != nil {
+		fmt.Println("  ┌─ RAMP UP AIMD TRAJECTORY (target RPS vs time) ────────────────")
+		rampPhase.AIMD.Print()
+		fmt.Println("  └────────────────────────────────────────────────────────────────")
+		fmt.Println()
+	}
 }
 
 // ─── Main ───────────────────────────────────────────────────────────────────
@@ -732,12 +1104,91 @@ func main() {
 	flag.IntVar(&cfg.SteadyRPS, "steady-rps", 50, "Steady state auth/sec")
 	flag.IntVar(&cfg.PeakRPS, "peak-rps", 1000, "Peak auth/sec target")
 
+	// SLO alarms — see AlarmStore. A threshold of 0 disables that rule.
+	flag.Float64Var(&cfg.AlarmErrorRate, "alarm-error-rate", 0,
+		"Fire an alarm if the error rate crosses this fraction, sustained for -alarm-window (0=disabled, e.g. 0.05 for 5%)")
+	flag.DurationVar(&cfg.AlarmP99, "alarm-p99", 0,
+		"Fire an alarm if p99 latency crosses this threshold, sustained for -alarm-window (0=disabled)")
+	flag.Float64Var(&cfg.AlarmTimeoutsRate, "alarm-timeouts-per-sec", 0,
+		"Fire an alarm if the timeout rate crosses this many/sec, sustained for -alarm-window (0=disabled)")
+	flag.DurationVar(&cfg.AlarmWindow, "alarm-window", 10*time.Second, "How long an SLO must stay breached before its alarm fires")
+	flag.StringVar(&cfg.AlarmPolicy, "alarm-policy", "warn", "What to do when an alarm fires: warn, throttle, or abort")
+
+	// Live metrics fork-out — see metrics.go
+	flag.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "Serve Prometheus /metrics on this address (e.g. :9090); disabled if empty")
+	flag.StringVar(&cfg.PushInflux, "push-influx", "", "InfluxDB line-protocol write URL to push per-second stats to (e.g. http://influxdb:8086/write?db=loadtest); disabled if empty")
+	flag.StringVar(&cfg.Iface, "iface", "", "NIC to sample packet/byte counters from while a phase runs (default: sum every non-loopback interface)")
+
+	// Accounting + CoA/DM — see session.go
+	flag.BoolVar(&cfg.Acct, "acct", false, "Simulate RFC 2866 accounting lifecycle (Start/Interim-Update/Stop) for every accepted session")
+	flag.IntVar(&cfg.AcctPort, "acct-port", 1813, "RADIUS accounting port")
+	flag.DurationVar(&cfg.AcctInterimInterval, "acct-interim", 5*time.Second,
+		"Interval between simulated Interim-Updates (compressed from the real ~5-15min cadence so sessions finish within a test run)")
+	flag.StringVar(&cfg.CoAListen, "coa-listen", ":3799", "UDP address for the built-in mock NAS to receive CoA-Request/Disconnect-Request on")
+	flag.StringVar(&cfg.CoATarget, "coa-target", "", "NAS address to send CoA-Request/Disconnect-Request against (defaults to -coa-listen, looping back to the built-in mock NAS)")
+	flag.IntVar(&cfg.CoARPS, "coa-rps", 20, "CoA-Request/Disconnect-Request requests/sec during the CoA/DM burst phase")
+	flag.DurationVar(&cfg.CoADuration, "coa-dur", 20*time.Second, "CoA/DM burst phase duration")
+
+	// NAS attribute injection — see attributes.go
+	flag.StringVar(&cfg.Profile, "profile", string(ProfileNokiaBNG),
+		"Vendor attribute shape for every Access-Request: nokia-bng, cisco-asr, or mikrotik")
+	flag.Float64Var(&cfg.MalformedPct, "malformed-pct", 0,
+		"Percent of Access-Requests to corrupt with a garbage VSA sub-attribute length (0=disabled)")
+
+	// Adaptive AIMD rate control — see aimd.go
+	flag.BoolVar(&cfg.Adaptive, "adaptive", false,
+		"Run Phase 2 (RAMP UP) as an AIMD search for max sustainable RPS instead of a fixed linear ramp")
+	flag.DurationVar(&cfg.SLOP95, "slo-p95", 200*time.Millisecond, "p95 latency ceiling the AIMD controller targets in -adaptive mode")
+	flag.Float64Var(&cfg.SLOErrorRate, "slo-err", 0.02, "Error rate ceiling (fraction, e.g. 0.02 for 2%) the AIMD controller targets in -adaptive mode")
+	flag.Float64Var(&cfg.AIMDIncrease, "aimd-increase", 50, "RPS added per second while under both SLOs in -adaptive mode")
+
+	// CI regression gates — see report.go's evaluatePhase. A threshold of 0 disables that check.
+	flag.Float64Var(&cfg.MaxRejectRate, "max-reject-rate", 0, "Fail a phase's report if its reject rate exceeds this fraction (0=disabled, e.g. 0.05 for 5%)")
+	flag.DurationVar(&cfg.MaxP99, "max-p99", 0, "Fail a phase's report if its p99 latency exceeds this ceiling (0=disabled)")
+	flag.DurationVar(&cfg.SteadySLO, "steady-slo", 0, "Fail STEADY STATE's report if its p99 latency exceeds this ceiling (0=disabled)")
+	flag.DurationVar(&cfg.PeakSLO, "peak-slo", 0, "Fail SUSTAINED PEAK's report if its p99 latency exceeds this ceiling (0=disabled)")
+	flag.DurationVar(&cfg.OutageRecoveryTime, "outage-recovery-time", 0, "Fail POWER OUTAGE's report if its duration exceeds this ceiling (0=disabled)")
+
+	// Scenario engine — see phase.go/scenario.go
+	flag.StringVar(&cfg.Scenario, "scenario", "", "YAML file listing phases to run instead of the built-in 4-phase story (see scenario.go for the format)")
+
+	flag.StringVar(&cfg.LogLevel, "log-level", "info", "Log level: debug, info, warn, or error (see STLOG for per-component overrides)")
+	flag.StringVar(&cfg.LogFile, "log-file", "", "Log sink: empty for stderr, a path for a file, or the literal value \"journald\"")
+	flag.BoolVar(&cfg.JSONLogs, "json-logs", false, "Emit logs as JSON lines instead of telegraf-style text")
+	flag.BoolVar(&cfg.Quiet, "quiet", false, "Raise the log level to warn, hiding phase banners and (unless STLOG names \"rate\") progress ticks; warnings and errors still show")
+
 	// Presets
 	quick := flag.Bool("quick", false, "Quick test (15s phases, 100K users)")
 	full := flag.Bool("full", false, "Full realistic test (longer phases, 100K users)")
 
 	flag.Parse()
 
+	log = newLoggerFromFlags(cfg.LogLevel, cfg.LogFile, cfg.JSONLogs, cfg.Quiet)
+
+	cfg.ReportDir = envOr("LOADTEST_REPORT_DIR", ".")
+	if spec := os.Getenv("LOADTEST_REPORT"); spec != "" {
+		cfg.ReportFormats = strings.Split(spec, ",")
+	}
+
+	var alarmRules []AlarmRule
+	policy := AlarmPolicy(cfg.AlarmPolicy)
+	if cfg.AlarmErrorRate > 0 {
+		alarmRules = append(alarmRules, AlarmRule{Type: AlarmErrorRate, Policy: policy, Window: cfg.AlarmWindow, ErrorRateThreshold: cfg.AlarmErrorRate})
+	}
+	if cfg.AlarmP99 > 0 {
+		alarmRules = append(alarmRules, AlarmRule{Type: AlarmLatencySLO, Policy: policy, Window: cfg.AlarmWindow, LatencyThreshold: cfg.AlarmP99})
+	}
+	if cfg.AlarmTimeoutsRate > 0 {
+		alarmRules = append(alarmRules, AlarmRule{Type: AlarmTimeoutStorm, Policy: policy, Window: cfg.AlarmWindow, TimeoutRateThreshold: cfg.AlarmTimeoutsRate})
+	}
+	alarms := NewAlarmStore(alarmRules)
+
+	pack, err := newAttributePack(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	if *quick {
 		cfg.SteadyDuration = 10 * time.Second
 		cfg.RampDuration = 15 * time.Second
@@ -776,10 +1227,32 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigCh
-		fmt.Println("\n  ⚠ Interrupted — finishing current phase...")
+		log.Warnf("phase", "interrupted — finishing current phase...")
 		cancel()
 	}()
 
+	if cfg.MetricsAddr != "" {
+		serveMetrics(cfg.MetricsAddr)
+		fmt.Printf("  Metrics: http://%s/metrics\n", cfg.MetricsAddr)
+	}
+	startDebugServer()
+	if cfg.PushInflux != "" {
+		go pushInflux(ctx, cfg.PushInflux, activeStats.Load)
+		fmt.Printf("  Pushing per-second stats to %s\n", cfg.PushInflux)
+	}
+
+	// The mock NAS loops CoA/DM traffic back to this process when -coa-target
+	// is unset, so the CoA/DM burst phase can be exercised without a real
+	// NAS under test.
+	if cfg.CoATarget == "" {
+		cfg.CoATarget = cfg.CoAListen
+		go func() {
+			if err := mockNAS(ctx, cfg.CoAListen, []byte(cfg.RadiusSecret)); err != nil {
+				fmt.Fprintf(os.Stderr, "  mock NAS on %s stopped: %v\n", cfg.CoAListen, err)
+			}
+		}()
+	}
+
 	// ── Inject users ────────────────────────────────────────────────
 	if cfg.ScaleUsers > 0 {
 		if err := injectSyntheticUsers(cfg.PgDSN, cfg.ScaleUsers); err != nil {
@@ -811,6 +1284,11 @@ func main() {
 	fmt.Printf("  Phases: steady=%s  ramp=%s  outage=%s  peak=%s\n",
 		cfg.SteadyDuration, cfg.RampDuration, cfg.OutageDuration, cfg.PeakDuration)
 	fmt.Printf("  Rates:  steady=%d/s  peak=%d/s\n", cfg.SteadyRPS, cfg.PeakRPS)
+	fmt.Printf("  NAS profile: %s", cfg.Profile)
+	if cfg.MalformedPct > 0 {
+		fmt.Printf("  (malformed=%.1f%%)", cfg.MalformedPct)
+	}
+	fmt.Println()
 
 	// ── Warmup ──────────────────────────────────────────────────────
 	fmt.Print("\n  Warmup: ")
@@ -818,97 +1296,77 @@ func main() {
 	secret := []byte(cfg.RadiusSecret)
 	for i := 0; i < 10 && i < len(users); i++ {
 		reqCtx, c := context.WithTimeout(ctx, cfg.Timeout)
-		doAuth(reqCtx, addr, secret, users[i])
+		doAuth(reqCtx, addr, secret, users[i], pack, false)
 		c()
 		fmt.Print(".")
 	}
 	fmt.Println(" done\n")
 
+	// acctStats accumulates every Accounting-Request this run sends, across
+	// all four auth phases (sessions are spawned from whichever phase their
+	// Access-Accept came from, but run on the outer ctx so they outlive it).
+	acctStats := &LiveStats{Phase: "ACCOUNTING"}
+	mainStart := time.Now()
+
 	var phases []PhaseSummary
 
 	// ═══════════════════════════════════════════════════════════════════
-	//  Phase 1: STEADY STATE — Normal ISP churn
+	//  Phases 1-4 (or -scenario's replacement list) — see phase.go
 	// ═══════════════════════════════════════════════════════════════════
-	if ctx.Err() == nil {
-		fmt.Println("━━━ Phase 1: STEADY STATE ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		fmt.Printf("  Simulating normal PPPoE churn: %d auth/sec for %s\n", cfg.SteadyRPS, cfg.SteadyDuration)
-		fmt.Println("  (Lease expiry, modem reboots, line flaps)")
-
-		s1 := &LiveStats{}
-		pCtx, pCancel := context.WithCancel(ctx)
-		startProgressTicker(pCtx, s1, "STEADY")
-
-		start := time.Now()
-		rateLimitedSend(ctx, cfg, users, s1, cfg.SteadyRPS, cfg.SteadyDuration, 100)
-		dur := time.Since(start)
-
-		pCancel()
-		printPhaseResult("STEADY STATE", s1, dur)
-		phases = append(phases, capturePhase("STEADY STATE", s1, dur))
+	enginePhases := defaultPhases(cfg)
+	if cfg.Scenario != "" {
+		loaded, err := loadScenario(cfg.Scenario)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load -scenario %s: %v\n", cfg.Scenario, err)
+			os.Exit(1)
+		}
+		enginePhases = loaded
+		log.Infof("phase", "scenario: %s (%d phases)", cfg.Scenario, len(enginePhases))
 	}
 
-	// ═══════════════════════════════════════════════════════════════════
-	//  Phase 2: RAMP UP — Morning peak
-	// ═══════════════════════════════════════════════════════════════════
-	if ctx.Err() == nil {
-		fmt.Println("\n━━━ Phase 2: RAMP UP ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		fmt.Printf("  Morning peak ramp: %d → %d auth/sec over %s\n",
-			cfg.SteadyRPS, cfg.PeakRPS, cfg.RampDuration)
-		fmt.Println("  (06:00-08:00 — subscribers come online)")
-
-		s2 := &LiveStats{}
-		pCtx, pCancel := context.WithCancel(ctx)
-		startProgressTicker(pCtx, s2, "RAMP  ")
-
-		start := time.Now()
-		rampSend(ctx, cfg, users, s2, cfg.SteadyRPS, cfg.PeakRPS, cfg.RampDuration, 300)
-		dur := time.Since(start)
-
-		pCancel()
-		printPhaseResult("RAMP UP", s2, dur)
-		phases = append(phases, capturePhase("RAMP UP", s2, dur))
+	pc := PhaseContext{Cfg: cfg, Users: users, AcctStats: acctStats, Pack: pack, Alarms: alarms}
+	for i, ph := range enginePhases {
+		if ctx.Err() != nil {
+			break
+		}
+		log.Infof("phase", "--- phase %d: %s ---", i+1, ph.Name())
+		phases = append(phases, ph.Run(ctx, pc))
 	}
 
 	// ═══════════════════════════════════════════════════════════════════
-	//  Phase 3: POWER OUTAGE RECOVERY — Mass reconnect
+	//  Phase 5: CoA/DM BURST — Operator bounce/reauthorize traffic
 	// ═══════════════════════════════════════════════════════════════════
 	if ctx.Err() == nil {
-		fmt.Println("\n━━━ Phase 3: POWER OUTAGE RECOVERY ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		fmt.Printf("  Power restored! %d CPEs rebooting over %s\n", len(users), cfg.OutageDuration)
-		fmt.Println("  (20%% fast boot 5-15s, 50%% normal 15-45s, 30%% slow 45-90s)")
+		fmt.Println("\n━━━ Phase 5: CoA/DM BURST ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("  CoA-Request/Disconnect-Request against %s: %d/sec for %s\n",
+			cfg.CoATarget, cfg.CoARPS, cfg.CoADuration)
+		fmt.Println("  (Operators bouncing or reauthorizing sessions — RFC 3576/5176)")
 
-		s3 := &LiveStats{}
+		s5 := &LiveStats{Phase: "COA/DM"}
+		activeStats.Store(s5)
 		pCtx, pCancel := context.WithCancel(ctx)
-		startProgressTicker(pCtx, s3, "OUTAGE")
+		startProgressTicker(pCtx, s5, "COA/DM", "COA/DM BURST", alarms, nil, pCancel)
+		res5 := newResourceSampler(cfg.Iface)
+		if res5 != nil {
+			go res5.run(pCtx)
+		}
 
 		start := time.Now()
-		outageBurst(ctx, cfg, users, s3, cfg.OutageDuration, 500)
+		coaBurst(pCtx, cfg, users, s5, cfg.CoATarget, cfg.CoARPS, cfg.CoADuration, 100)
 		dur := time.Since(start)
 
 		pCancel()
-		printPhaseResult("POWER OUTAGE", s3, dur)
-		phases = append(phases, capturePhase("POWER OUTAGE", s3, dur))
+		printPhaseResult("COA/DM BURST", s5, dur, reportOrNil(res5))
+		phases = append(phases, capturePhase("COA/DM BURST", s5, dur, alarms, res5))
 	}
 
-	// ═══════════════════════════════════════════════════════════════════
-	//  Phase 4: SUSTAINED PEAK — Max throughput
-	// ═══════════════════════════════════════════════════════════════════
-	if ctx.Err() == nil {
-		fmt.Println("\n━━━ Phase 4: SUSTAINED PEAK ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		fmt.Printf("  Continuous %d auth/sec for %s\n", cfg.PeakRPS, cfg.PeakDuration)
-		fmt.Println("  (Finding sustained throughput ceiling)")
-
-		s4 := &LiveStats{}
-		pCtx, pCancel := context.WithCancel(ctx)
-		startProgressTicker(pCtx, s4, "PEAK  ")
-
-		start := time.Now()
-		rateLimitedSend(ctx, cfg, users, s4, cfg.PeakRPS, cfg.PeakDuration, 500)
-		dur := time.Since(start)
-
-		pCancel()
-		printPhaseResult("SUSTAINED PEAK", s4, dur)
-		phases = append(phases, capturePhase("SUSTAINED PEAK", s4, dur))
+	// Accounting runs continuously alongside every auth phase above (each
+	// Access-Accept spawns its own Start/Interim/Stop goroutine on the outer
+	// ctx), so it's captured once at the end rather than per-phase. Give any
+	// still-open sessions a moment to flush their final Stop first.
+	if cfg.Acct {
+		time.Sleep(2 * time.Second)
+		phases = append(phases, capturePhase("ACCOUNTING", acctStats, time.Since(mainStart), alarms, nil))
 	}
 
 	// ═══════════════════════════════════════════════════════════════════
@@ -916,6 +1374,7 @@ func main() {
 	// ═══════════════════════════════════════════════════════════════════
 	if len(phases) > 0 {
 		printFinalReport(phases, len(users))
+		writeReports(phases, len(users), cfg)
 	}
 }
 
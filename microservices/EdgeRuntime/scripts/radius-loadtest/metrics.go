@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the Prometheus collectors served on -metrics-addr, labeled by
+// phase so a Grafana dashboard can split STEADY STATE/RAMP UP/POWER
+// OUTAGE/SUSTAINED PEAK rather than averaging them together.
+var metrics = struct {
+	RequestsTotal  *prometheus.CounterVec
+	LatencySeconds *prometheus.HistogramVec
+	Inflight       prometheus.Gauge
+	CurrentRPS     prometheus.Gauge
+
+	PhaseRPS      *prometheus.GaugeVec
+	PhaseAccepts  *prometheus.GaugeVec
+	PhaseRejects  *prometheus.GaugeVec
+	PhaseTimeouts *prometheus.GaugeVec
+	PhaseInflight *prometheus.GaugeVec
+}{
+	RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "radiusloadtest_requests_total",
+		Help: "Auth requests completed, labeled by result (accept/reject/error) and phase.",
+	}, []string{"result", "phase"}),
+	LatencySeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "radiusloadtest_latency_seconds",
+		Help:    "Auth round-trip latency, labeled by phase. Buckets mirror the internal latency histogram (see main.go).",
+		Buckets: prometheusLatencyBuckets(),
+	}, []string{"phase"}),
+	Inflight: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "radiusloadtest_inflight_requests",
+		Help: "Auth requests currently in flight, across all phases.",
+	}),
+	CurrentRPS: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "radiusloadtest_instant_rps",
+		Help: "Requests/sec over the most recent 2s progress-ticker window.",
+	}),
+
+	PhaseRPS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "radiusloadtest_phase_rps",
+		Help: "Requests/sec over the most recent 2s progress-ticker window, labeled by phase tag.",
+	}, []string{"phase"}),
+	PhaseAccepts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "radiusloadtest_phase_accepts_total",
+		Help: "Cumulative Access-Accepts for the running phase, labeled by phase tag.",
+	}, []string{"phase"}),
+	PhaseRejects: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "radiusloadtest_phase_rejects_total",
+		Help: "Cumulative Access-Rejects for the running phase, labeled by phase tag.",
+	}, []string{"phase"}),
+	PhaseTimeouts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "radiusloadtest_phase_timeouts_total",
+		Help: "Cumulative request timeouts for the running phase, labeled by phase tag.",
+	}, []string{"phase"}),
+	PhaseInflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "radiusloadtest_phase_inflight_requests",
+		Help: "Requests currently in flight for the running phase, labeled by phase tag.",
+	}, []string{"phase"}),
+}
+
+func init() {
+	prometheus.MustRegister(
+		metrics.RequestsTotal, metrics.LatencySeconds, metrics.Inflight, metrics.CurrentRPS,
+		metrics.PhaseRPS, metrics.PhaseAccepts, metrics.PhaseRejects, metrics.PhaseTimeouts, metrics.PhaseInflight,
+	)
+}
+
+// prometheusLatencyBuckets downsamples histBucketBounds (800 entries, see
+// main.go) by a fixed stride rather than choosing Prometheus buckets
+// independently, so the /metrics histogram's resolution is derived from the
+// same log-spaced scale as PrintHistogram's report instead of drifting from
+// it over time.
+func prometheusLatencyBuckets() []float64 {
+	const stride = 32
+	buckets := make([]float64, 0, histNumBuckets/stride+1)
+	for i := 0; i < histNumBuckets; i += stride {
+		buckets = append(buckets, time.Duration(histBucketBounds[i]).Seconds())
+	}
+	return buckets
+}
+
+// serveMetrics exposes /metrics in Prometheus exposition format on addr in
+// the background, logging (rather than crashing the load test) if the
+// listener dies.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Errorf("metrics", "server on %s stopped: %v", addr, err)
+		}
+	}()
+}
+
+// debugServerAddr is where startDebugServer listens when LOADTEST_METRICS
+// and/or LOADTEST_PPROF are set, independent of -metrics-addr. It's a fixed
+// address rather than a flag since it's meant for ad hoc attach-a-profiler
+// use, not for the scrape target operators wire into Grafana.
+const debugServerAddr = ":6060"
+
+// startDebugServer mounts /metrics and/or /debug/pprof/* on debugServerAddr
+// per the LOADTEST_METRICS/LOADTEST_PPROF env vars, independently of each
+// other and of -metrics-addr, so an operator can attach pprof to a run
+// without also standing up a scrape target. A no-op if neither is set.
+func startDebugServer() {
+	metricsOn := os.Getenv("LOADTEST_METRICS") == "1"
+	pprofOn := os.Getenv("LOADTEST_PPROF") == "1"
+	if !metricsOn && !pprofOn {
+		return
+	}
+
+	mux := http.NewServeMux()
+	if metricsOn {
+		mux.Handle("/metrics", promhttp.Handler())
+	}
+	if pprofOn {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	go func() {
+		if err := http.ListenAndServe(debugServerAddr, mux); err != nil {
+			log.Errorf("metrics", "debug server on %s stopped: %v", debugServerAddr, err)
+		}
+	}()
+
+	fmt.Printf("  Debug: http://%s", debugServerAddr)
+	switch {
+	case metricsOn && pprofOn:
+		fmt.Printf(" (/metrics, /debug/pprof)\n")
+	case metricsOn:
+		fmt.Printf(" (/metrics)\n")
+	case pprofOn:
+		fmt.Printf(" (/debug/pprof)\n")
+	}
+}
+
+// activeStats points at whichever phase's LiveStats is currently running;
+// phases run strictly sequentially, so a single pointer is enough context
+// for pushInflux to always read "the current phase's live stats".
+var activeStats atomic.Pointer[LiveStats]
+
+// pushInflux POSTs a line-protocol point of getStats()'s current snapshot to
+// an InfluxDB /write endpoint (url) once a second until ctx is canceled.
+// Errors are logged and the loop continues, since a dead metrics backend
+// shouldn't abort the load test itself.
+func pushInflux(ctx context.Context, url string, getStats func() *LiveStats) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := getStats()
+			if stats == nil {
+				continue
+			}
+			total, accept, reject, errors, avgMs, maxMs := stats.Snapshot()
+			p50, p95, p99 := stats.Percentiles()
+
+			line := fmt.Sprintf(
+				"radiusloadtest,phase=%s total=%di,accept=%di,reject=%di,errors=%di,avg_ms=%f,max_ms=%f,p50_ms=%f,p95_ms=%f,p99_ms=%f\n",
+				stats.Phase, total, accept, reject, errors, avgMs, maxMs,
+				p50.Seconds()*1000, p95.Seconds()*1000, p99.Seconds()*1000,
+			)
+
+			resp, err := client.Post(url, "text/plain; charset=utf-8", bytes.NewBufferString(line))
+			if err != nil {
+				log.Warnf("metrics", "influx push to %s failed: %v", url, err)
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				log.Warnf("metrics", "influx push to %s returned %s", url, resp.Status)
+			}
+		}
+	}
+}
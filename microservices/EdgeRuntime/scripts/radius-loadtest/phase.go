@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// ─── Pluggable phase engine ──────────────────────────────────────────────────
+//
+// main() used to wire Phase 1-4 as four copy-pasted `if ctx.Err() == nil { ...
+// }` blocks. Phase is the common signature that replaces them: each phase
+// manages its own LiveStats/progress-ticker/resource-sampler lifecycle (via
+// runPhase) and hands back a PhaseSummary, the same shape capturePhase has
+// always produced. defaultPhases returns the original four, driven by -steady-*
+// /-ramp-*/-outage-*/-peak-* flags; -scenario loads a YAML list instead (see
+// scenario.go), so an operator can describe a site-specific run without
+// recompiling.
+
+// PhaseContext carries the dependencies every phase needs, so adding a new
+// Phase implementation doesn't mean growing Run's parameter list again.
+type PhaseContext struct {
+	Cfg       Config
+	Users     []User
+	AcctStats *LiveStats
+	Pack      *AttributePack
+	Alarms    *AlarmStore
+}
+
+// Phase is one stage of a load test run: a named traffic pattern that reports
+// back a PhaseSummary for the final report.
+type Phase interface {
+	Name() string
+	Run(ctx context.Context, pc PhaseContext) PhaseSummary
+}
+
+// runPhase is the shared boilerplate every Phase.Run delegates to: stand up
+// this phase's LiveStats, progress ticker, and resource sampler, run send
+// until it returns, then tear them down and capture the result. useGov
+// controls whether an rpsGovernor is created (and so whether an AlarmStore
+// throttle policy can act on this phase) — outage/burst-style phases that
+// don't target a fixed RPS pass false.
+func runPhase(ctx context.Context, cfg Config, name, tag string, alarms *AlarmStore, useGov bool,
+	send func(pCtx context.Context, stats *LiveStats, gov *rpsGovernor)) PhaseSummary {
+
+	stats := &LiveStats{Phase: name}
+	activeStats.Store(stats)
+
+	var gov *rpsGovernor
+	if useGov {
+		gov = newRPSGovernor()
+	}
+
+	pCtx, pCancel := context.WithCancel(ctx)
+	startProgressTicker(pCtx, stats, tag, name, alarms, gov, pCancel)
+	res := newResourceSampler(cfg.Iface)
+	if res != nil {
+		go res.run(pCtx)
+	}
+
+	start := time.Now()
+	send(pCtx, stats, gov)
+	dur := time.Since(start)
+
+	pCancel()
+	printPhaseResult(name, stats, dur, reportOrNil(res))
+	return capturePhase(name, stats, dur, alarms, res)
+}
+
+// ─── SteadyPhase — normal ISP churn ──────────────────────────────────────────
+
+// SteadyPhase sends a fixed auth/sec rate for Duration, simulating lease
+// expiry, modem reboots, and line flaps.
+type SteadyPhase struct {
+	RPS      int
+	Duration time.Duration
+}
+
+func (SteadyPhase) Name() string { return "STEADY STATE" }
+
+func (p SteadyPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	log.Infof("phase", "STEADY STATE: simulating normal PPPoE churn, %d auth/sec for %s (lease expiry, modem reboots, line flaps)", p.RPS, p.Duration)
+	return runPhase(ctx, pc.Cfg, p.Name(), "STEADY", pc.Alarms, true, func(pCtx context.Context, stats *LiveStats, gov *rpsGovernor) {
+		rateLimitedSend(pCtx, pc.Cfg, pc.Users, stats, pc.AcctStats, p.RPS, p.Duration, 100, gov, pc.Pack)
+	})
+}
+
+// ─── RampPhase — morning peak ────────────────────────────────────────────────
+
+// RampPhase ramps from StartRPS to EndRPS over Duration, or — when Adaptive
+// is set — runs cfg's AIMD search for max sustainable RPS instead.
+type RampPhase struct {
+	StartRPS int
+	EndRPS   int
+	Duration time.Duration
+	Adaptive bool
+}
+
+func (RampPhase) Name() string { return "RAMP UP" }
+
+func (p RampPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	var aimd *aimdController
+	if p.Adaptive {
+		aimd = newAIMDController(p.StartRPS, pc.Cfg.AIMDIncrease, pc.Cfg.SLOP95, pc.Cfg.SLOErrorRate)
+		log.Infof("phase", "RAMP UP: adaptive AIMD search from %d auth/sec over %s (slo-p95=%s slo-err=%.1f%% +%.0f/s) (06:00-08:00, subscribers come online)",
+			p.StartRPS, p.Duration, pc.Cfg.SLOP95, pc.Cfg.SLOErrorRate*100, pc.Cfg.AIMDIncrease)
+	} else {
+		log.Infof("phase", "RAMP UP: morning peak ramp, %d -> %d auth/sec over %s (06:00-08:00, subscribers come online)", p.StartRPS, p.EndRPS, p.Duration)
+	}
+
+	summary := runPhase(ctx, pc.Cfg, p.Name(), "RAMP  ", pc.Alarms, true, func(pCtx context.Context, stats *LiveStats, gov *rpsGovernor) {
+		rampSend(pCtx, pc.Cfg, pc.Users, stats, pc.AcctStats, p.StartRPS, p.EndRPS, p.Duration, 300, gov, pc.Pack, aimd)
+	})
+	if aimd != nil {
+		report := aimd.Report()
+		summary.AIMD = &report
+	}
+	return summary
+}
+
+// ─── OutageBurstPhase — mass reconnect storm ─────────────────────────────────
+
+// OutageBurstPhase staggers every user's re-auth over Duration as if every
+// CPE rebooted simultaneously after a power restore.
+type OutageBurstPhase struct {
+	Duration time.Duration
+}
+
+func (OutageBurstPhase) Name() string { return "POWER OUTAGE" }
+
+func (p OutageBurstPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	log.Infof("phase", "POWER OUTAGE: power restored, %d CPEs rebooting over %s (20%% fast boot 5-15s, 50%% normal 15-45s, 30%% slow 45-90s)", len(pc.Users), p.Duration)
+	return runPhase(ctx, pc.Cfg, p.Name(), "OUTAGE", pc.Alarms, false, func(pCtx context.Context, stats *LiveStats, _ *rpsGovernor) {
+		outageBurst(pCtx, pc.Cfg, pc.Users, stats, pc.AcctStats, p.Duration, 500, pc.Pack)
+	})
+}
+
+// ─── PeakPhase — sustained max throughput ────────────────────────────────────
+
+// PeakPhase sends a fixed auth/sec rate for Duration at higher concurrency
+// than SteadyPhase, looking for the server's sustained throughput ceiling.
+type PeakPhase struct {
+	RPS      int
+	Duration time.Duration
+}
+
+func (PeakPhase) Name() string { return "SUSTAINED PEAK" }
+
+func (p PeakPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	log.Infof("phase", "SUSTAINED PEAK: continuous %d auth/sec for %s (finding sustained throughput ceiling)", p.RPS, p.Duration)
+	return runPhase(ctx, pc.Cfg, p.Name(), "PEAK  ", pc.Alarms, true, func(pCtx context.Context, stats *LiveStats, gov *rpsGovernor) {
+		rateLimitedSend(pCtx, pc.Cfg, pc.Users, stats, pc.AcctStats, p.RPS, p.Duration, 500, gov, pc.Pack)
+	})
+}
+
+// defaultPhases returns the original Phase 1-4 story (STEADY STATE, RAMP UP,
+// POWER OUTAGE, SUSTAINED PEAK), parameterized from cfg exactly as main()
+// always wired them. Used when -scenario is empty.
+func defaultPhases(cfg Config) []Phase {
+	return []Phase{
+		SteadyPhase{RPS: cfg.SteadyRPS, Duration: cfg.SteadyDuration},
+		RampPhase{StartRPS: cfg.SteadyRPS, EndRPS: cfg.PeakRPS, Duration: cfg.RampDuration, Adaptive: cfg.Adaptive},
+		OutageBurstPhase{Duration: cfg.OutageDuration},
+		PeakPhase{RPS: cfg.PeakRPS, Duration: cfg.PeakDuration},
+	}
+}
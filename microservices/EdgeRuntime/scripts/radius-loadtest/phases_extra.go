@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+// ─── Phases beyond the ISP-morning-peak story ───────────────────────────────
+//
+// defaultPhases (see phase.go) only ever told one story: churn, ramp, outage,
+// peak. These four are the other real-world ISP failure modes a YAML
+// -scenario can ask for: a background trickle of flapping lines, an OLT
+// rolling reboot, a denial-of-service-shaped reject storm, and a long soak
+// run to surface what the per-phase resource sampling (see resources.go) is
+// actually for — a slow memory leak.
+
+// ─── FlapPhase — background line flapping ───────────────────────────────────
+
+// FlapPhase re-authenticates a random Fraction of users at Poisson-distributed
+// intervals averaging 1/Rate seconds apart, simulating the background churn
+// of DSL/PPPoE lines that flap independently of any broader event.
+type FlapPhase struct {
+	Fraction float64 // 0-1, fraction of the user pool that flaps
+	Rate     float64 // average flaps/sec across the flapping subset
+	Duration time.Duration
+}
+
+func (FlapPhase) Name() string { return "FLAP" }
+
+func (p FlapPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	subset := randomSubset(pc.Users, p.Fraction)
+	log.Infof("phase", "FLAP: %d/%d lines flapping at ~%.1f/sec (Poisson) for %s (background DSL/PPPoE line flaps, independent of any broader event)", len(subset), len(pc.Users), p.Rate, p.Duration)
+	return runPhase(ctx, pc.Cfg, p.Name(), "FLAP  ", pc.Alarms, false, func(pCtx context.Context, stats *LiveStats, _ *rpsGovernor) {
+		flapSend(pCtx, pc.Cfg, subset, stats, pc.AcctStats, p.Rate, p.Duration, pc.Pack)
+	})
+}
+
+// randomSubset returns a random subset of users of size fraction*len(users)
+// (at least 1, given a non-empty users and fraction > 0).
+func randomSubset(users []User, fraction float64) []User {
+	n := int(float64(len(users)) * fraction)
+	if n < 1 {
+		n = 1
+	}
+	if n > len(users) {
+		n = len(users)
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	shuffled := make([]User, len(users))
+	copy(shuffled, users)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return shuffled[:n]
+}
+
+// flapSend fires one auth request at a time against a random member of users,
+// waiting a Poisson-distributed (exponentially-distributed) interval
+// averaging 1/avgRate seconds between requests, until duration elapses.
+func flapSend(ctx context.Context, cfg Config, users []User, stats, acctStats *LiveStats,
+	avgRate float64, duration time.Duration, pack *AttributePack) {
+
+	if avgRate <= 0 || duration <= 0 || len(users) == 0 {
+		return
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.RadiusHost, cfg.RadiusPort)
+	secret := []byte(cfg.RadiusSecret)
+	sem := make(chan struct{}, 50)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	deadline := time.After(duration)
+	var wg sync.WaitGroup
+
+	for {
+		interval := time.Duration(rng.ExpFloat64() / avgRate * float64(time.Second))
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			wg.Wait()
+			return
+		case <-deadline:
+			timer.Stop()
+			wg.Wait()
+			return
+		case <-timer.C:
+			user := users[rng.Intn(len(users))]
+			malformed := pack.Malformed(rng)
+			sem <- struct{}{}
+			wg.Add(1)
+			metrics.Inflight.Inc()
+			stats.Inflight.Add(1)
+			go func(u User, malformed bool) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				defer metrics.Inflight.Dec()
+				defer stats.Inflight.Add(-1)
+				reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+				defer cancel()
+				r := doAuth(reqCtx, addr, secret, u, pack, malformed)
+				stats.Record(r)
+				if cfg.Acct && r.Success {
+					go runAccountingSession(ctx, cfg, u, acctStats)
+				}
+			}(user, malformed)
+		}
+	}
+}
+
+// ─── RollingRebootPhase — staggered OLT card reboot ─────────────────────────
+
+// RollingRebootPhase splits the user pool into Cards equal groups and reboots
+// them one at a time: each card's users re-auth in a staggered burst over
+// CardWindow (reusing outageBurst's boot-time distribution), then the phase
+// waits Stagger before moving to the next card — an OLT reboot schedule
+// rather than every CPE in the network rebooting at once.
+type RollingRebootPhase struct {
+	Cards      int
+	CardWindow time.Duration
+	Stagger    time.Duration
+}
+
+func (RollingRebootPhase) Name() string { return "ROLLING REBOOT" }
+
+func (p RollingRebootPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	cards := p.Cards
+	if cards < 1 {
+		cards = 1
+	}
+	log.Infof("phase", "ROLLING REBOOT: rebooting %d OLT card(s) one at a time, %s/card + %s stagger (scheduled card reboot, not a mass power-outage reconnect)", cards, p.CardWindow, p.Stagger)
+
+	return runPhase(ctx, pc.Cfg, p.Name(), "REBOOT", pc.Alarms, false, func(pCtx context.Context, stats *LiveStats, _ *rpsGovernor) {
+		cardSize := (len(pc.Users) + cards - 1) / cards
+		if cardSize < 1 {
+			cardSize = 1
+		}
+		for i := 0; i < cards; i++ {
+			if pCtx.Err() != nil {
+				return
+			}
+			lo := i * cardSize
+			if lo >= len(pc.Users) {
+				break
+			}
+			hi := lo + cardSize
+			if hi > len(pc.Users) {
+				hi = len(pc.Users)
+			}
+
+			outageBurst(pCtx, pc.Cfg, pc.Users[lo:hi], stats, pc.AcctStats, p.CardWindow, 200, pc.Pack)
+
+			if i < cards-1 {
+				select {
+				case <-pCtx.Done():
+					return
+				case <-time.After(p.Stagger):
+				}
+			}
+		}
+	})
+}
+
+// ─── RejectStormPhase — wrong-secret/wrong-password attack traffic ──────────
+
+// RejectStormPhase sends Access-Requests doomed to fail (RejectMode) at RPS
+// for Duration, measuring how the server behaves under a sustained stream of
+// bad credentials — a brute-force/credential-stuffing attack, or a
+// misconfigured NAS secret rolled out to a whole network.
+type RejectStormPhase struct {
+	RPS        int
+	Duration   time.Duration
+	RejectMode string // "bad-secret" or "bad-password" (default)
+}
+
+func (RejectStormPhase) Name() string { return "REJECT STORM" }
+
+func (p RejectStormPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	mode := p.RejectMode
+	if mode == "" {
+		mode = "bad-password"
+	}
+	log.Infof("phase", "REJECT STORM: %d/sec deliberately-rejected auths (%s) for %s (credential-stuffing / misconfigured-secret attack traffic)", p.RPS, mode, p.Duration)
+	return runPhase(ctx, pc.Cfg, p.Name(), "REJECT", pc.Alarms, false, func(pCtx context.Context, stats *LiveStats, _ *rpsGovernor) {
+		rejectStormSend(pCtx, pc.Cfg, pc.Users, stats, p.RPS, p.Duration, 100, pc.Pack, mode)
+	})
+}
+
+// doRejectAuth sends an Access-Request doomed to be rejected: mode
+// "bad-secret" signs the request (and encodes User-Password) with a secret
+// one byte off from cfg's real one, "bad-password" sends the right secret
+// with a wrong password.
+func doRejectAuth(ctx context.Context, addr string, secret []byte, user User, pack *AttributePack, mode string) Result {
+	sendSecret := secret
+	password := user.Password
+	if mode == "bad-secret" {
+		sendSecret = append(append([]byte{}, secret...), '!')
+	} else {
+		password = password + "-wrong"
+	}
+
+	pkt := radius.New(radius.CodeAccessRequest, sendSecret)
+	rfc2865.UserName_SetString(pkt, user.Username)
+	rfc2865.UserPassword_SetString(pkt, password)
+	pack.Apply(pkt, user, false)
+
+	start := time.Now()
+	resp, err := radius.Exchange(ctx, pkt, addr)
+	lat := time.Since(start)
+	if err != nil {
+		return Result{Latency: lat, Error: true, Timeout: isTimeoutErr(err)}
+	}
+	return Result{
+		Latency: lat,
+		Success: resp.Code == radius.CodeAccessAccept,
+		Reject:  resp.Code == radius.CodeAccessReject,
+	}
+}
+
+// rejectStormSend is rateLimitedSend's doRejectAuth counterpart: a fixed-RPS
+// sender with no AIMD/governor hookup, since a reject storm's point is to
+// hold a steady attack rate, not search for a sustainable one.
+func rejectStormSend(ctx context.Context, cfg Config, users []User, stats *LiveStats,
+	rps int, duration time.Duration, concurrency int, pack *AttributePack, mode string) {
+
+	if rps <= 0 || duration <= 0 || len(users) == 0 {
+		return
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.RadiusHost, cfg.RadiusPort)
+	secret := []byte(cfg.RadiusSecret)
+	sem := make(chan struct{}, concurrency)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+	deadline := time.After(duration)
+	var wg sync.WaitGroup
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-deadline:
+			wg.Wait()
+			return
+		case <-ticker.C:
+			user := users[rng.Intn(len(users))]
+			sem <- struct{}{}
+			wg.Add(1)
+			metrics.Inflight.Inc()
+			stats.Inflight.Add(1)
+			go func(u User) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				defer metrics.Inflight.Dec()
+				defer stats.Inflight.Add(-1)
+				reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+				defer cancel()
+				stats.Record(doRejectAuth(reqCtx, addr, secret, u, pack, mode))
+			}(user)
+		}
+	}
+}
+
+// ─── SoakPhase — long low-rate leak hunt ────────────────────────────────────
+
+// SoakPhase holds a low, steady auth/sec rate for a long Duration. Its
+// distinguishing feature isn't the send pattern (identical to SteadyPhase) —
+// it's running long enough for the resourceSampler's RSSPeakMB (see
+// resources.go) to expose a slow memory leak that a 30-60s phase never would.
+type SoakPhase struct {
+	RPS      int
+	Duration time.Duration
+}
+
+func (SoakPhase) Name() string { return "SOAK" }
+
+func (p SoakPhase) Run(ctx context.Context, pc PhaseContext) PhaseSummary {
+	log.Infof("phase", "SOAK: %d auth/sec for %s (watching RSS for a leak, see chunk5-2)", p.RPS, p.Duration)
+	return runPhase(ctx, pc.Cfg, p.Name(), "SOAK  ", pc.Alarms, true, func(pCtx context.Context, stats *LiveStats, gov *rpsGovernor) {
+		rateLimitedSend(pCtx, pc.Cfg, pc.Users, stats, pc.AcctStats, p.RPS, p.Duration, 50, gov, pc.Pack)
+	})
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── Machine-readable phase reports ─────────────────────────────────────────
+//
+// printFinalReport's ASCII tables are for a human watching the run. A
+// nightly job instead wants the same phases slice rendered as JSON/CSV for a
+// dashboard and as JUnit XML so a CI system can fail the pipeline on
+// regression without a human eyeballing anything. Select formats with
+// LOADTEST_REPORT=json,csv,junit (comma-separated) and LOADTEST_REPORT_DIR
+// for where files land (default ".").
+
+// phaseVerdict is a phase's pass/fail against cfg's SLOs, shared by every
+// Reporter so JSON/CSV/JUnit agree on what "failed" means.
+type phaseVerdict struct {
+	Failed bool
+	Reason string
+}
+
+// evaluatePhase checks p against the global -max-reject-rate/-max-p99
+// ceilings plus whichever per-phase SLO applies to p.Name, joining every
+// violation into Reason so a CI log shows all of them at once instead of
+// just the first.
+func evaluatePhase(p PhaseSummary, cfg Config) phaseVerdict {
+	rejectRate := 0.0
+	if p.Total > 0 {
+		rejectRate = float64(p.Reject) / float64(p.Total)
+	}
+
+	var reasons []string
+	if cfg.MaxRejectRate > 0 && rejectRate > cfg.MaxRejectRate {
+		reasons = append(reasons, fmt.Sprintf("reject rate %.2f%% exceeds -max-reject-rate %.2f%%",
+			rejectRate*100, cfg.MaxRejectRate*100))
+	}
+	if cfg.MaxP99 > 0 && p.P99 > cfg.MaxP99 {
+		reasons = append(reasons, fmt.Sprintf("p99 %s exceeds -max-p99 %s", fmtDur(p.P99), fmtDur(cfg.MaxP99)))
+	}
+
+	switch p.Name {
+	case "STEADY STATE":
+		if cfg.SteadySLO > 0 && p.P99 > cfg.SteadySLO {
+			reasons = append(reasons, fmt.Sprintf("p99 %s exceeds -steady-slo %s", fmtDur(p.P99), fmtDur(cfg.SteadySLO)))
+		}
+	case "SUSTAINED PEAK":
+		if cfg.PeakSLO > 0 && p.P99 > cfg.PeakSLO {
+			reasons = append(reasons, fmt.Sprintf("p99 %s exceeds -peak-slo %s", fmtDur(p.P99), fmtDur(cfg.PeakSLO)))
+		}
+	case "POWER OUTAGE":
+		if cfg.OutageRecoveryTime > 0 && p.Duration > cfg.OutageRecoveryTime {
+			reasons = append(reasons, fmt.Sprintf("duration %s exceeds -outage-recovery-time %s",
+				p.Duration.Round(time.Second), cfg.OutageRecoveryTime))
+		}
+	}
+
+	return phaseVerdict{Failed: len(reasons) > 0, Reason: strings.Join(reasons, "; ")}
+}
+
+// Reporter renders the final phases slice to dir in one output format.
+// Implementations are looked up by name from LOADTEST_REPORT; a Reporter
+// that fails to write is logged and skipped rather than aborting the run,
+// since the load test itself already completed successfully.
+type Reporter interface {
+	Name() string
+	Write(dir string, phases []PhaseSummary, userCount int, cfg Config) error
+}
+
+// newReporters builds the Reporters named in spec (comma-separated, as read
+// from LOADTEST_REPORT). An unknown name is logged and skipped.
+func newReporters(spec string) []Reporter {
+	var reporters []Reporter
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "":
+			continue
+		case "json":
+			reporters = append(reporters, jsonReporter{})
+		case "csv":
+			reporters = append(reporters, csvReporter{})
+		case "junit":
+			reporters = append(reporters, junitReporter{})
+		default:
+			fmt.Printf("[warn] unknown LOADTEST_REPORT entry %q\n", name)
+		}
+	}
+	return reporters
+}
+
+// writeReports runs every Reporter named in cfg.ReportFormats against dir,
+// logging (rather than failing the run over) any individual write error.
+func writeReports(phases []PhaseSummary, userCount int, cfg Config) {
+	reporters := newReporters(strings.Join(cfg.ReportFormats, ","))
+	if len(reporters) == 0 {
+		return
+	}
+	dir := cfg.ReportDir
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Printf("[warn] report dir %s: %v\n", dir, err)
+		return
+	}
+	for _, r := range reporters {
+		if err := r.Write(dir, phases, userCount, cfg); err != nil {
+			fmt.Printf("[warn] %s report: %v\n", r.Name(), err)
+			continue
+		}
+		fmt.Printf("  Report (%s): %s\n", r.Name(), dir)
+	}
+}
+
+// ─── JSON ────────────────────────────────────────────────────────────────
+
+type jsonPhaseReport struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"duration_ms"`
+	Total      int64   `json:"total"`
+	Accept     int64   `json:"accept"`
+	Reject     int64   `json:"reject"`
+	Errors     int64   `json:"errors"`
+	ErrorPct   float64 `json:"error_pct"`
+	RejectRate float64 `json:"reject_rate"`
+	AvgMs      float64 `json:"avg_ms"`
+	MaxMs      float64 `json:"max_ms"`
+	P50Ms      float64 `json:"p50_ms"`
+	P95Ms      float64 `json:"p95_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	RPS        float64 `json:"rps"`
+	Failed     bool    `json:"failed"`
+	FailReason string  `json:"fail_reason,omitempty"`
+}
+
+type jsonSummary struct {
+	UserCount int     `json:"user_count"`
+	Phases    int     `json:"phase_count"`
+	Total     int64   `json:"total"`
+	Accept    int64   `json:"accept"`
+	Reject    int64   `json:"reject"`
+	Errors    int64   `json:"errors"`
+	ErrorPct  float64 `json:"error_pct"`
+	Failed    bool    `json:"failed"`
+}
+
+type jsonReport struct {
+	Summary jsonSummary       `json:"summary"`
+	Phases  []jsonPhaseReport `json:"phases"`
+}
+
+// toJSONPhase converts p (plus its pass/fail verdict) to the shape shared by
+// the JSON and CSV reporters, so the two never drift on field derivation.
+func toJSONPhase(p PhaseSummary, v phaseVerdict) jsonPhaseReport {
+	rejectRate := 0.0
+	errPct := 0.0
+	if p.Total > 0 {
+		rejectRate = float64(p.Reject) / float64(p.Total)
+		errPct = float64(p.Errors) / float64(p.Total) * 100
+	}
+	return jsonPhaseReport{
+		Name:       p.Name,
+		DurationMs: float64(p.Duration.Microseconds()) / 1000,
+		Total:      p.Total, Accept: p.Accept, Reject: p.Reject, Errors: p.Errors,
+		ErrorPct: errPct, RejectRate: rejectRate,
+		AvgMs: p.AvgMs, MaxMs: p.MaxMs,
+		P50Ms: p.P50.Seconds() * 1000, P95Ms: p.P95.Seconds() * 1000, P99Ms: p.P99.Seconds() * 1000,
+		RPS: p.RPS, Failed: v.Failed, FailReason: v.Reason,
+	}
+}
+
+type jsonReporter struct{}
+
+func (jsonReporter) Name() string { return "json" }
+
+func (jsonReporter) Write(dir string, phases []PhaseSummary, userCount int, cfg Config) error {
+	report := jsonReport{Summary: jsonSummary{UserCount: userCount, Phases: len(phases)}}
+	for _, p := range phases {
+		v := evaluatePhase(p, cfg)
+		report.Phases = append(report.Phases, toJSONPhase(p, v))
+
+		report.Summary.Total += p.Total
+		report.Summary.Accept += p.Accept
+		report.Summary.Reject += p.Reject
+		report.Summary.Errors += p.Errors
+		report.Summary.Failed = report.Summary.Failed || v.Failed
+	}
+	if report.Summary.Total > 0 {
+		report.Summary.ErrorPct = float64(report.Summary.Errors) / float64(report.Summary.Total) * 100
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, "report.json"), data, 0o644)
+}
+
+// ─── CSV ─────────────────────────────────────────────────────────────────
+
+type csvReporter struct{}
+
+func (csvReporter) Name() string { return "csv" }
+
+func (csvReporter) Write(dir string, phases []PhaseSummary, userCount int, cfg Config) error {
+	f, err := os.Create(filepath.Join(dir, "report.csv"))
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	header := []string{"phase", "duration_ms", "total", "accept", "reject", "errors",
+		"error_pct", "reject_rate", "avg_ms", "max_ms", "p50_ms", "p95_ms", "p99_ms", "rps", "failed", "fail_reason"}
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, p := range phases {
+		jp := toJSONPhase(p, evaluatePhase(p, cfg))
+		row := []string{
+			jp.Name,
+			strconv.FormatFloat(jp.DurationMs, 'f', -1, 64),
+			strconv.FormatInt(jp.Total, 10),
+			strconv.FormatInt(jp.Accept, 10),
+			strconv.FormatInt(jp.Reject, 10),
+			strconv.FormatInt(jp.Errors, 10),
+			strconv.FormatFloat(jp.ErrorPct, 'f', -1, 64),
+			strconv.FormatFloat(jp.RejectRate, 'f', -1, 64),
+			strconv.FormatFloat(jp.AvgMs, 'f', -1, 64),
+			strconv.FormatFloat(jp.MaxMs, 'f', -1, 64),
+			strconv.FormatFloat(jp.P50Ms, 'f', -1, 64),
+			strconv.FormatFloat(jp.P95Ms, 'f', -1, 64),
+			strconv.FormatFloat(jp.P99Ms, 'f', -1, 64),
+			strconv.FormatFloat(jp.RPS, 'f', -1, 64),
+			strconv.FormatBool(jp.Failed),
+			jp.FailReason,
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+	}
+	return w.Error()
+}
+
+// ─── JUnit XML ───────────────────────────────────────────────────────────
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitReporter struct{}
+
+func (junitReporter) Name() string { return "junit" }
+
+// Write renders each phase as a <testcase>, failing (per evaluatePhase) when
+// its reject rate or p99 latency breaches cfg's global or per-phase SLOs —
+// the regression gate a nightly CI job greps go-junit-report-style output
+// for.
+func (junitReporter) Write(dir string, phases []PhaseSummary, userCount int, cfg Config) error {
+	suite := junitTestSuite{Name: "radius-loadtest"}
+	var totalDur time.Duration
+
+	for _, p := range phases {
+		v := evaluatePhase(p, cfg)
+		totalDur += p.Duration
+		tc := junitTestCase{
+			Name:      p.Name,
+			Classname: "radius-loadtest",
+			Time:      fmt.Sprintf("%.3f", p.Duration.Seconds()),
+		}
+		if v.Failed {
+			tc.Failure = &junitFailure{Message: v.Reason, Text: v.Reason}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	suite.Time = fmt.Sprintf("%.3f", totalDur.Seconds())
+
+	data, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	out := append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(dir, "junit.xml"), out, 0o644)
+}
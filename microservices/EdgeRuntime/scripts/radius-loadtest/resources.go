@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ─── Host resource sampling ─────────────────────────────────────────────────
+//
+// When SUSTAINED PEAK reports a throughput ceiling there's otherwise no way
+// to tell whether the ceiling is the server, the network, or this process.
+// resourceSampler ticks once a second while a phase runs and records the
+// load generator's own CPU/load-average/RSS/NIC-packet footprint alongside
+// it, the same way the telegraf SystemStats collector pulls load.LoadAvg(),
+// host.Info(), and CPU/net counters.
+
+// ResourceReport summarizes a resourceSampler's run for the final report.
+type ResourceReport struct {
+	CPUAvgPct   float64
+	CPUPeakPct  float64
+	Load1Peak   float64
+	RSSPeakMB   float64
+	PPSSentPeak float64
+	PPSSentAvg  float64
+
+	// Starved is set when the load generator itself looked saturated during
+	// the phase (CPU >90%, or more than 2s, twice the 1s tick, passed between
+	// two of the sampler's samples), meaning this phase's throughput/latency numbers
+	// may reflect the generator's own ceiling rather than the server's.
+	Starved bool
+}
+
+// resourceSampler ticks once a second against the current process and, for
+// NIC counters, either -iface or the sum of every non-loopback interface.
+// One sampler is created per phase so its peaks/averages are scoped to that
+// phase alone, mirroring aimdController's per-phase lifetime.
+type resourceSampler struct {
+	iface string
+	proc  *process.Process
+
+	mu        sync.Mutex
+	cpuSum    float64
+	cpuN      int
+	cpuPeak   float64
+	load1Peak float64
+	rssPeakMB float64
+	ppsSum    float64
+	ppsN      int
+	ppsPeak   float64
+	starved   bool
+
+	lastSent uint64
+	lastAt   time.Time
+}
+
+// newResourceSampler builds a sampler for the current process, returning nil
+// if gopsutil can't even look up this PID — the caller should skip resource
+// sampling for the run rather than fail it outright.
+func newResourceSampler(iface string) *resourceSampler {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil
+	}
+	return &resourceSampler{iface: iface, proc: proc}
+}
+
+// run ticks once a second, sampling CPU/load/RSS/NIC counters, until ctx is
+// canceled. The caller starts this alongside startProgressTicker and cancels
+// both at phase end so the sampler's lifetime matches exactly one phase.
+func (rs *resourceSampler) run(ctx context.Context) {
+	rs.lastAt = time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rs.sample()
+		}
+	}
+}
+
+func (rs *resourceSampler) sample() {
+	now := time.Now()
+	elapsed := now.Sub(rs.lastAt)
+	rs.lastAt = now
+
+	cpuPct := 0.0
+	if pcts, err := cpu.Percent(0, false); err == nil && len(pcts) > 0 {
+		cpuPct = pcts[0]
+	}
+
+	load1 := 0.0
+	if avg, err := load.Avg(); err == nil {
+		load1 = avg.Load1
+	}
+
+	rssMB := 0.0
+	if mi, err := rs.proc.MemoryInfo(); err == nil && mi != nil {
+		rssMB = float64(mi.RSS) / (1024 * 1024)
+	}
+
+	var sent uint64
+	if counters, err := net.IOCounters(true); err == nil {
+		for _, c := range counters {
+			if rs.iface != "" {
+				if c.Name == rs.iface {
+					sent += c.PacketsSent
+				}
+				continue
+			}
+			if !isLoopback(c.Name) {
+				sent += c.PacketsSent
+			}
+		}
+	}
+
+	pps := 0.0
+	if rs.lastSent > 0 && elapsed > 0 {
+		pps = float64(sent-rs.lastSent) / elapsed.Seconds()
+	}
+	rs.lastSent = sent
+
+	// >2x the 1s tick interval means this goroutine itself went unscheduled
+	// for a while, which is as strong a "don't trust these numbers" signal
+	// as a high CPU% reading.
+	schedStarved := elapsed > 2*time.Second
+
+	rs.mu.Lock()
+	rs.cpuSum += cpuPct
+	rs.cpuN++
+	if cpuPct > rs.cpuPeak {
+		rs.cpuPeak = cpuPct
+	}
+	if load1 > rs.load1Peak {
+		rs.load1Peak = load1
+	}
+	if rssMB > rs.rssPeakMB {
+		rs.rssPeakMB = rssMB
+	}
+	if pps > 0 {
+		rs.ppsSum += pps
+		rs.ppsN++
+	}
+	if pps > rs.ppsPeak {
+		rs.ppsPeak = pps
+	}
+	if cpuPct > 90 || schedStarved {
+		rs.starved = true
+	}
+	rs.mu.Unlock()
+}
+
+// isLoopback is a cheap lo/lo0 name check rather than pulling interface
+// flags via net.Interfaces(), since gopsutil's net.IOCountersStat doesn't
+// carry them.
+func isLoopback(name string) bool {
+	return name == "lo" || name == "lo0" || strings.HasPrefix(name, "lo:")
+}
+
+// reportOrNil is the *resourceSampler -> *ResourceReport nil-safe bridge
+// between a phase's sampler (may be nil when gopsutil couldn't resolve this
+// process) and printPhaseResult/capturePhase, which both take a report.
+func reportOrNil(rs *resourceSampler) *ResourceReport {
+	if rs == nil {
+		return nil
+	}
+	r := rs.Report()
+	return &r
+}
+
+// Report snapshots the sampler's accumulated stats for the final report.
+func (rs *resourceSampler) Report() ResourceReport {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	cpuAvg := 0.0
+	if rs.cpuN > 0 {
+		cpuAvg = rs.cpuSum / float64(rs.cpuN)
+	}
+	ppsAvg := 0.0
+	if rs.ppsN > 0 {
+		ppsAvg = rs.ppsSum / float64(rs.ppsN)
+	}
+
+	return ResourceReport{
+		CPUAvgPct: cpuAvg, CPUPeakPct: rs.cpuPeak,
+		Load1Peak: rs.load1Peak, RSSPeakMB: rs.rssPeakMB,
+		PPSSentPeak: rs.ppsPeak, PPSSentAvg: ppsAvg,
+		Starved: rs.starved,
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ─── YAML scenario files ────────────────────────────────────────────────────
+//
+// -scenario nightly.yaml replaces defaultPhases with a list an operator
+// describes themselves:
+//
+//   phases:
+//     - type: steady
+//       rps: 50
+//       duration: 30s
+//     - type: flap
+//       fraction: 0.05
+//       rate: 10
+//       duration: 5m
+//     - type: reject_storm
+//       rps: 200
+//       duration: 30s
+//       mode: bad-password
+//
+// Durations are parsed with time.ParseDuration (e.g. "30s", "5m"), matching
+// every -*-dur flag's format, so an operator copy-pasting a flag value into
+// YAML doesn't have to convert units.
+
+// PhaseSpec is one YAML list entry under `phases:`. Every phase type reads
+// only the fields it needs; unused fields in a given entry are ignored
+// rather than rejected, so a scenario file can keep a column layout across
+// mixed phase types without one type's irrelevant zero-values erroring out.
+type PhaseSpec struct {
+	Type     string  `yaml:"type"`
+	RPS      int     `yaml:"rps"`
+	StartRPS int     `yaml:"start_rps"`
+	EndRPS   int     `yaml:"end_rps"`
+	Duration string  `yaml:"duration"`
+	Adaptive bool    `yaml:"adaptive"`
+	Fraction float64 `yaml:"fraction"`
+	Rate     float64 `yaml:"rate"`
+	Cards    int     `yaml:"cards"`
+	Window   string  `yaml:"window"`
+	Stagger  string  `yaml:"stagger"`
+	Mode     string  `yaml:"mode"`
+}
+
+// ScenarioFile is the top-level shape of a -scenario YAML document.
+type ScenarioFile struct {
+	Phases []PhaseSpec `yaml:"phases"`
+}
+
+// loadScenario reads and parses path, building a Phase per entry in document
+// order. A spec with an unknown type or an unparseable duration fails the
+// whole load — a typo in a CI-gating scenario file should stop the run, not
+// silently skip a phase.
+func loadScenario(path string) ([]Phase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var file ScenarioFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	phases := make([]Phase, 0, len(file.Phases))
+	for i, spec := range file.Phases {
+		phase, err := buildPhase(spec)
+		if err != nil {
+			return nil, fmt.Errorf("phase %d (%s): %w", i+1, spec.Type, err)
+		}
+		phases = append(phases, phase)
+	}
+	return phases, nil
+}
+
+// parseDur parses s with time.ParseDuration, treating "" as 0 rather than an
+// error so a spec can omit fields it doesn't use.
+func parseDur(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// buildPhase constructs the Phase spec.Type names, parsing its duration
+// fields against that type's requirements.
+func buildPhase(spec PhaseSpec) (Phase, error) {
+	duration, err := parseDur(spec.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("duration %q: %w", spec.Duration, err)
+	}
+
+	switch spec.Type {
+	case "steady":
+		return SteadyPhase{RPS: spec.RPS, Duration: duration}, nil
+	case "ramp":
+		return RampPhase{StartRPS: spec.StartRPS, EndRPS: spec.EndRPS, Duration: duration, Adaptive: spec.Adaptive}, nil
+	case "outage":
+		return OutageBurstPhase{Duration: duration}, nil
+	case "peak":
+		return PeakPhase{RPS: spec.RPS, Duration: duration}, nil
+	case "flap":
+		return FlapPhase{Fraction: spec.Fraction, Rate: spec.Rate, Duration: duration}, nil
+	case "rolling_reboot":
+		window, err := parseDur(spec.Window)
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %w", spec.Window, err)
+		}
+		stagger, err := parseDur(spec.Stagger)
+		if err != nil {
+			return nil, fmt.Errorf("stagger %q: %w", spec.Stagger, err)
+		}
+		return RollingRebootPhase{Cards: spec.Cards, CardWindow: window, Stagger: stagger}, nil
+	case "reject_storm":
+		switch spec.Mode {
+		case "", "bad-password", "bad-secret":
+		default:
+			return nil, fmt.Errorf("unknown mode %q (want bad-password or bad-secret)", spec.Mode)
+		}
+		return RejectStormPhase{RPS: spec.RPS, Duration: duration, RejectMode: spec.Mode}, nil
+	case "soak":
+		return SoakPhase{RPS: spec.RPS, Duration: duration}, nil
+	default:
+		return nil, fmt.Errorf("unknown phase type %q", spec.Type)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2866"
+)
+
+// ─── Accounting session lifecycle (RFC 2866) ────────────────────────────────
+
+// runAccountingSession simulates one subscriber's accounting lifecycle after
+// its Access-Accept: a Start, a handful of Interim-Updates carrying
+// incremented Acct-Input-Octets/Acct-Output-Octets, and a final Stop. The
+// real ~5-15min Interim-Update cadence is compressed to
+// cfg.AcctInterimInterval so a session finishes within a load test's
+// lifetime. ctx canceling early (the run shutting down) sends the Stop
+// immediately instead of waiting out the remaining interims.
+func runAccountingSession(ctx context.Context, cfg Config, user User, stats *LiveStats) {
+	addr := fmt.Sprintf("%s:%d", cfg.RadiusHost, cfg.AcctPort)
+	secret := []byte(cfg.RadiusSecret)
+	sessionID := fmt.Sprintf("loadtest-%s-%d", user.Username, time.Now().UnixNano())
+
+	var inputOctets, outputOctets, sessionTime uint32
+
+	send := func(status rfc2866.AcctStatusType_Value) {
+		pkt := radius.New(radius.CodeAccountingRequest, secret)
+		rfc2865.UserName_SetString(pkt, user.Username)
+		rfc2866.AcctSessionId_SetString(pkt, sessionID)
+		rfc2866.AcctStatusType_Set(pkt, status)
+		rfc2866.AcctInputOctets_Set(pkt, inputOctets)
+		rfc2866.AcctOutputOctets_Set(pkt, outputOctets)
+		rfc2866.AcctSessionTime_Set(pkt, sessionTime)
+
+		// The Stop gets a fresh timeout instead of one derived from ctx, so
+		// it still goes out after ctx has been canceled.
+		parent := ctx
+		if status == rfc2866.AcctStatusType_Value_Stop {
+			parent = context.Background()
+		}
+		reqCtx, cancel := context.WithTimeout(parent, cfg.Timeout)
+		defer cancel()
+		start := time.Now()
+		_, err := radius.Exchange(reqCtx, pkt, addr)
+		lat := time.Since(start)
+		if err != nil {
+			stats.Record(Result{Latency: lat, Error: true, Timeout: isTimeoutErr(err)})
+			return
+		}
+		stats.Record(Result{Latency: lat, Success: true})
+	}
+
+	send(rfc2866.AcctStatusType_Value_Start)
+
+	interims := 1 + rand.Intn(4) // 1-4 Interim-Updates before Stop
+	timer := time.NewTimer(cfg.AcctInterimInterval)
+	defer timer.Stop()
+
+	for i := 0; i < interims; i++ {
+		select {
+		case <-ctx.Done():
+			send(rfc2866.AcctStatusType_Value_Stop)
+			return
+		case <-timer.C:
+			sessionTime += uint32(cfg.AcctInterimInterval.Seconds())
+			inputOctets += uint32(50_000 + rand.Intn(500_000))
+			outputOctets += uint32(5_000 + rand.Intn(50_000))
+			send(rfc2866.AcctStatusType_Value_InterimUpdate)
+			timer.Reset(cfg.AcctInterimInterval)
+		}
+	}
+
+	send(rfc2866.AcctStatusType_Value_Stop)
+}
+
+// ─── CoA/Disconnect-Message burst (RFC 3576/5176) ───────────────────────────
+
+// doCoA sends a single CoA-Request or Disconnect-Request against addr,
+// mirroring doAuth's shape so its latency can be recorded into the same
+// LiveStats type.
+func doCoA(ctx context.Context, addr string, secret []byte, user User, disconnect bool) Result {
+	code := radius.CodeCoARequest
+	if disconnect {
+		code = radius.CodeDisconnectRequest
+	}
+	pkt := radius.New(code, secret)
+	rfc2865.UserName_SetString(pkt, user.Username)
+
+	start := time.Now()
+	resp, err := radius.Exchange(ctx, pkt, addr)
+	lat := time.Since(start)
+
+	if err != nil {
+		return Result{Latency: lat, Error: true, Timeout: isTimeoutErr(err)}
+	}
+	ack := resp.Code == radius.CodeCoAACK || resp.Code == radius.CodeDisconnectACK
+	return Result{Latency: lat, Success: ack, Reject: !ack}
+}
+
+// coaBurst sends CoA-Request/Disconnect-Request packets against target at
+// rps, simulating an operator bouncing or reauthorizing sessions. 30% of
+// requests are Disconnect-Message, the rest CoA-Request. Returns when ctx is
+// canceled or duration expires.
+func coaBurst(ctx context.Context, cfg Config, users []User, stats *LiveStats, target string, rps int, duration time.Duration, concurrency int) {
+	if rps <= 0 || duration <= 0 {
+		return
+	}
+
+	secret := []byte(cfg.RadiusSecret)
+	sem := make(chan struct{}, concurrency)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+	deadline := time.After(duration)
+	var wg sync.WaitGroup
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-deadline:
+			wg.Wait()
+			return
+		case <-ticker.C:
+			user := users[rng.Intn(len(users))]
+			disconnect := rng.Float64() < 0.3
+			sem <- struct{}{}
+			wg.Add(1)
+			metrics.Inflight.Inc()
+			go func(u User, dm bool) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				defer metrics.Inflight.Dec()
+				reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+				defer cancel()
+				r := doCoA(reqCtx, target, secret, u, dm)
+				stats.Record(r)
+			}(user, disconnect)
+		}
+	}
+}
+
+// mockNAS answers CoA-Request/Disconnect-Request packets with ACKs so
+// coaBurst can be exercised in isolation without a real NAS under test; it's
+// started whenever -coa-target is left empty, looping coaBurst back against
+// this listener.
+func mockNAS(ctx context.Context, addr string, secret []byte) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("resolve -coa-listen address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return fmt.Errorf("bind mock NAS on %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 4096)
+	for {
+		n, raddr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil // conn closed via ctx.Done() above
+		}
+
+		pkt, err := radius.Parse(buf[:n], secret)
+		if err != nil {
+			continue
+		}
+
+		var ackCode radius.Code
+		switch pkt.Code {
+		case radius.CodeCoARequest:
+			ackCode = radius.CodeCoAACK
+		case radius.CodeDisconnectRequest:
+			ackCode = radius.CodeDisconnectACK
+		default:
+			continue
+		}
+
+		resp := radius.New(ackCode, secret)
+		resp.Identifier = pkt.Identifier
+		data, err := resp.Encode()
+		if err != nil {
+			continue
+		}
+		conn.WriteToUDP(data, raddr)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/md4"
+)
+
+// AuthMethod selects which RADIUS authentication scheme TestAuthenticationMethod drives.
+type AuthMethod string
+
+const (
+	AuthPAP         AuthMethod = "PAP"
+	AuthCHAP        AuthMethod = "CHAP"
+	AuthMSCHAPv2    AuthMethod = "MSCHAPv2"
+	AuthEAPMD5      AuthMethod = "EAP-MD5"
+	AuthEAPMSCHAPv2 AuthMethod = "EAP-MSCHAPv2"
+
+	maxEAPRoundTrips = 10
+)
+
+// EAP codes and types (RFC 3748).
+const (
+	eapCodeRequest  byte = 1
+	eapCodeResponse byte = 2
+	eapCodeSuccess  byte = 3
+	eapCodeFailure  byte = 4
+
+	eapTypeIdentity byte = 1
+	eapTypeMD5      byte = 4
+	eapTypeMSCHAPv2 byte = 26
+)
+
+// TestAuthenticationMethod runs the Access-Request/Access-Challenge round
+// trips required by method and returns the final result.
+func TestAuthenticationMethod(config *Config, username, password string, method AuthMethod) (*AuthResult, error) {
+	switch method {
+	case "", AuthPAP:
+		result, err := TestAuthentication(config, username, password)
+		if result != nil {
+			result.Method = AuthPAP
+			result.RoundTrips = 1
+		}
+		return result, err
+	case AuthCHAP:
+		return testCHAP(config, username, password)
+	case AuthMSCHAPv2:
+		return testMSCHAPv2(config, username, password)
+	case AuthEAPMD5, AuthEAPMSCHAPv2:
+		return testEAP(config, username, password, method)
+	default:
+		return nil, fmt.Errorf("unsupported auth method: %s", method)
+	}
+}
+
+// testCHAP sends a single CHAP-Password Access-Request: CHAP-Password =
+// MD5(CHAP-Identifier || password || CHAP-Challenge), per RFC 2865 §2.2.
+func testCHAP(config *Config, username, password string) (*AuthResult, error) {
+	challenge := make([]byte, 16)
+	rand.Read(challenge)
+	chapID := byte(nextIdentifier())
+
+	h := md5.New()
+	h.Write([]byte{chapID})
+	h.Write([]byte(password))
+	h.Write(challenge)
+	chapPassword := append([]byte{chapID}, h.Sum(nil)...)
+
+	req := NewPacket(CodeAccessRequest, nextIdentifier(), []byte(config.RadiusSecret))
+	req.AttrString(AttrUserName, username)
+	req.Add(AttrCHAPChallenge, challenge)
+	req.Add(AttrCHAPPassword, chapPassword)
+	if ip := net.ParseIP(config.NASIPAddress); ip != nil {
+		req.AttrIP(AttrNASIPAddress, ip)
+	}
+
+	resp, rtt, err := transport(config).Exchange(authAddr(config), req)
+	if err != nil {
+		return nil, fmt.Errorf("CHAP authentication failed: %w", err)
+	}
+
+	return &AuthResult{
+		Username:   username,
+		Success:    resp.Code == CodeAccessAccept,
+		Code:       resp.Code,
+		Attributes: resp.Attributes,
+		RTT:        rtt,
+		Method:     AuthCHAP,
+		RoundTrips: 1,
+	}, nil
+}
+
+// Microsoft vendor-specific attribute numbers used by MS-CHAP-v2 (RFC 2548).
+const (
+	vendorMicrosoft           uint32 = 311
+	vendorTypeMSCHAPChallenge byte   = 11
+	vendorTypeMSCHAP2Response byte   = 25
+)
+
+// addVSA appends a Vendor-Specific attribute (RFC 2865 §5.26): the 4-octet
+// vendor ID followed by a vendor-type/vendor-length/value sub-TLV.
+func addVSA(p *Packet, vendorID uint32, vendorType byte, value []byte) {
+	v := make([]byte, 0, 6+len(value))
+	v = append(v, byte(vendorID>>24), byte(vendorID>>16), byte(vendorID>>8), byte(vendorID))
+	v = append(v, vendorType, byte(len(value)+2))
+	v = append(v, value...)
+	p.Add(26, v)
+}
+
+// testMSCHAPv2 sends non-EAP MS-CHAP-v2 (RFC 2548): an MS-CHAP-Challenge plus
+// an MS-CHAP2-Response VSA computed with the same RFC 2759 algorithm used for
+// EAP-MSCHAPv2.
+func testMSCHAPv2(config *Config, username, password string) (*AuthResult, error) {
+	authChallenge := make([]byte, 16)
+	rand.Read(authChallenge)
+	peerChallenge := make([]byte, 16)
+	rand.Read(peerChallenge)
+
+	ntResponse, err := generateNTResponse(authChallenge, peerChallenge, username, password)
+	if err != nil {
+		return nil, fmt.Errorf("MS-CHAPv2 response build failed: %w", err)
+	}
+
+	msChapID := byte(nextIdentifier())
+	chap2Response := make([]byte, 0, 50)
+	chap2Response = append(chap2Response, msChapID)
+	chap2Response = append(chap2Response, peerChallenge...)
+	chap2Response = append(chap2Response, make([]byte, 8)...) // reserved
+	chap2Response = append(chap2Response, ntResponse...)
+	chap2Response = append(chap2Response, 0) // flags
+
+	req := NewPacket(CodeAccessRequest, nextIdentifier(), []byte(config.RadiusSecret))
+	req.AttrString(AttrUserName, username)
+	addVSA(req, vendorMicrosoft, vendorTypeMSCHAPChallenge, append([]byte{msChapID}, authChallenge...))
+	addVSA(req, vendorMicrosoft, vendorTypeMSCHAP2Response, chap2Response)
+	if ip := net.ParseIP(config.NASIPAddress); ip != nil {
+		req.AttrIP(AttrNASIPAddress, ip)
+	}
+
+	resp, rtt, err := transport(config).Exchange(authAddr(config), req)
+	if err != nil {
+		return nil, fmt.Errorf("MS-CHAPv2 authentication failed: %w", err)
+	}
+
+	return &AuthResult{
+		Username:   username,
+		Success:    resp.Code == CodeAccessAccept,
+		Code:       resp.Code,
+		Attributes: resp.Attributes,
+		RTT:        rtt,
+		Method:     AuthMSCHAPv2,
+		RoundTrips: 1,
+	}, nil
+}
+
+// testEAP drives the Access-Request → Access-Challenge → Access-Request loop
+// required for EAP methods, concatenating fragmented EAP-Message attributes
+// and attaching Message-Authenticator on every packet as required by RFC 3579 §3.2.
+func testEAP(config *Config, username, password string, method AuthMethod) (*AuthResult, error) {
+	secret := []byte(config.RadiusSecret)
+
+	// Round 1: identity response.
+	eapID := byte(1)
+	eapPayload := eapPacket(eapCodeResponse, eapID, eapTypeIdentity, []byte(username))
+	resp, rtt, err := sendEAPRequest(config, username, eapPayload, nil)
+	if err != nil {
+		return nil, fmt.Errorf("EAP identity round trip failed: %w", err)
+	}
+
+	roundTrips := 1
+	for roundTrips < maxEAPRoundTrips {
+		if resp.Code == CodeAccessAccept || resp.Code == CodeAccessReject {
+			return &AuthResult{
+				Username:   username,
+				Success:    resp.Code == CodeAccessAccept,
+				Code:       resp.Code,
+				Attributes: resp.Attributes,
+				RTT:        rtt,
+				Method:     method,
+				RoundTrips: roundTrips,
+			}, nil
+		}
+		if resp.Code != CodeAccessChallenge {
+			return nil, fmt.Errorf("unexpected code %d mid-EAP", resp.Code)
+		}
+
+		challenge := concatEAPMessage(resp)
+		if len(challenge) < 5 {
+			return nil, fmt.Errorf("malformed EAP-Message in Access-Challenge")
+		}
+		eapID = challenge[1]
+		eapType := challenge[4]
+
+		var eapData []byte
+		switch eapType {
+		case eapTypeMD5:
+			eapData, err = eapMD5Response(challenge, password)
+		case eapTypeMSCHAPv2:
+			eapData, err = eapMSCHAPv2Response(challenge, username, password)
+		default:
+			return nil, fmt.Errorf("unsupported EAP type %d", eapType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("EAP response build failed: %w", err)
+		}
+
+		eapPayload = eapPacket(eapCodeResponse, eapID, eapType, eapData)
+		resp, rtt, err = sendEAPRequest(config, username, eapPayload, secret)
+		if err != nil {
+			return nil, fmt.Errorf("EAP round trip %d failed: %w", roundTrips+1, err)
+		}
+		roundTrips++
+	}
+
+	return nil, fmt.Errorf("EAP exchange exceeded %d round trips", maxEAPRoundTrips)
+}
+
+// sendEAPRequest wraps an EAP payload (possibly fragmented) in an
+// Access-Request and attaches a Message-Authenticator, per RFC 3579.
+func sendEAPRequest(config *Config, username string, eapPayload []byte, secret []byte) (*Packet, time.Duration, error) {
+	req := NewPacket(CodeAccessRequest, nextIdentifier(), []byte(config.RadiusSecret))
+	req.AttrString(AttrUserName, username)
+	if ip := net.ParseIP(config.NASIPAddress); ip != nil {
+		req.AttrIP(AttrNASIPAddress, ip)
+	}
+	addEAPMessage(req, eapPayload)
+	req.Add(AttrMessageAuthenticator, make([]byte, 16))
+	if err := signMessageAuthenticator(req); err != nil {
+		return nil, 0, fmt.Errorf("sign message-authenticator: %w", err)
+	}
+
+	resp, rtt, err := transport(config).Exchange(authAddr(config), req)
+	return resp, rtt, err
+}
+
+// addEAPMessage splits payload across one or more EAP-Message attributes
+// (RFC 3579 §3.1), each at most 253 octets, in order.
+func addEAPMessage(p *Packet, payload []byte) {
+	const maxFrag = 253
+	for len(payload) > 0 {
+		n := len(payload)
+		if n > maxFrag {
+			n = maxFrag
+		}
+		p.Add(AttrEAPMessage, payload[:n])
+		payload = payload[n:]
+	}
+}
+
+// concatEAPMessage reassembles a (possibly fragmented) EAP-Message.
+func concatEAPMessage(p *Packet) []byte {
+	var out []byte
+	for _, frag := range p.GetAll(AttrEAPMessage) {
+		out = append(out, frag...)
+	}
+	return out
+}
+
+// eapPacket builds a single EAP packet: Code | Identifier | Length | Type | TypeData.
+func eapPacket(code, identifier, typ byte, typeData []byte) []byte {
+	length := 5 + len(typeData)
+	out := make([]byte, 0, length)
+	out = append(out, code, identifier, byte(length>>8), byte(length), typ)
+	out = append(out, typeData...)
+	return out
+}
+
+// eapMD5Response computes the EAP-MD5-Challenge response: MD5(EAP-Identifier || password || challenge).
+func eapMD5Response(challengePkt []byte, password string) ([]byte, error) {
+	if len(challengePkt) < 6 {
+		return nil, fmt.Errorf("EAP-MD5 challenge too short")
+	}
+	valueLen := int(challengePkt[5])
+	if len(challengePkt) < 6+valueLen {
+		return nil, fmt.Errorf("EAP-MD5 challenge truncated")
+	}
+	challenge := challengePkt[6 : 6+valueLen]
+	eapID := challengePkt[1]
+
+	h := md5.New()
+	h.Write([]byte{eapID})
+	h.Write([]byte(password))
+	h.Write(challenge)
+	digest := h.Sum(nil)
+
+	out := append([]byte{byte(len(digest))}, digest...)
+	return out, nil
+}
+
+// eapMSCHAPv2Response implements RFC 2759's GenerateNTResponse over the
+// Authentication-Challenge / Peer-Challenge exchanged in the EAP-MSCHAPv2
+// (RFC 2548 / draft-kamath-pppext-eap-mschapv2) Request.
+func eapMSCHAPv2Response(challengePkt []byte, username, password string) ([]byte, error) {
+	if len(challengePkt) < 6 {
+		return nil, fmt.Errorf("EAP-MSCHAPv2 request too short")
+	}
+	// MS-CHAP-V2 sub-field layout: OpCode(1) MS-CHAPv2-ID(1) MS-Length(2) Value-Size(1) Challenge(16) ...
+	body := challengePkt[5:]
+	if len(body) < 21 {
+		return nil, fmt.Errorf("EAP-MSCHAPv2 challenge sub-field truncated")
+	}
+	chapv2ID := body[1]
+	authChallenge := body[5:21]
+
+	peerChallenge := make([]byte, 16)
+	rand.Read(peerChallenge)
+
+	ntResponse, err := generateNTResponse(authChallenge, peerChallenge, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response sub-field: OpCode=2(Response) MS-CHAPv2-ID MS-Length Value-Size(49)
+	//   Peer-Challenge(16) Reserved(8) NT-Response(24) Flags(1) Name
+	const valueSize = 49
+	out := make([]byte, 0, 5+valueSize+len(username))
+	out = append(out, 2, chapv2ID, 0, 0, valueSize)
+	out = append(out, peerChallenge...)
+	out = append(out, make([]byte, 8)...)
+	out = append(out, ntResponse...)
+	out = append(out, 0) // Flags
+	out = append(out, []byte(username)...)
+
+	// MS-Length (big-endian) over the whole sub-field, written after building it.
+	out[2] = byte(len(out) >> 8)
+	out[3] = byte(len(out))
+	return out, nil
+}
+
+// generateNTResponse implements RFC 2759 §8.1: ChallengeHash →
+// NtPasswordHash → ChallengeResponse.
+func generateNTResponse(authChallenge, peerChallenge []byte, username, password string) ([]byte, error) {
+	challenge := challengeHash(peerChallenge, authChallenge, username)
+	passwordHash := ntPasswordHash(password)
+	return challengeResponse(challenge, passwordHash)
+}
+
+// challengeHash implements RFC 2759 §8.2 (ChallengeHash).
+func challengeHash(peerChallenge, authChallenge []byte, username string) []byte {
+	h := sha1.New()
+	h.Write(peerChallenge)
+	h.Write(authChallenge)
+	h.Write([]byte(username))
+	return h.Sum(nil)[:8]
+}
+
+// ntPasswordHash implements RFC 2759 §8.3 (NtPasswordHash): MD4 over the
+// UTF-16LE password.
+func ntPasswordHash(password string) []byte {
+	utf16le := make([]byte, 0, len(password)*2)
+	for _, r := range password {
+		utf16le = append(utf16le, byte(r), byte(r>>8))
+	}
+	h := md4.New()
+	h.Write(utf16le)
+	return h.Sum(nil)
+}
+
+// challengeResponse implements RFC 2759 §8.5 (ChallengeResponse): the
+// 8-byte challenge hashed and encrypted in three DES blocks keyed from the
+// 16-byte NT password hash, zero-padded to 21 bytes.
+func challengeResponse(challenge, passwordHash []byte) ([]byte, error) {
+	padded := make([]byte, 21)
+	copy(padded, passwordHash)
+
+	response := make([]byte, 24)
+	keys := [3][]byte{padded[0:7], padded[7:14], padded[14:21]}
+	for i, key7 := range keys {
+		block, err := des.NewCipher(expandDESKey(key7))
+		if err != nil {
+			return nil, fmt.Errorf("des key setup: %w", err)
+		}
+		block.Encrypt(response[i*8:i*8+8], challenge)
+	}
+	return response, nil
+}
+
+// expandDESKey expands a 7-byte key into the 8-byte form DES expects,
+// inserting a parity bit (unchecked) after every 7 bits, per RFC 2759 §8.4.
+func expandDESKey(key7 []byte) []byte {
+	var key8 [8]byte
+	key8[0] = key7[0] >> 1
+	key8[1] = (key7[0]<<6 | key7[1]>>2) & 0xFF
+	key8[2] = (key7[1]<<5 | key7[2]>>3) & 0xFF
+	key8[3] = (key7[2]<<4 | key7[3]>>4) & 0xFF
+	key8[4] = (key7[3]<<3 | key7[4]>>5) & 0xFF
+	key8[5] = (key7[4]<<2 | key7[5]>>6) & 0xFF
+	key8[6] = (key7[5]<<1 | key7[6]>>7) & 0xFF
+	key8[7] = key7[6] & 0x7F
+	for i, b := range key8 {
+		key8[i] = b << 1
+	}
+	return key8[:]
+}
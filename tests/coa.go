@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// coaListenPort is the well-known CoA/Disconnect port (RFC 5176 §3.3).
+const coaListenPort = 3799
+
+// coaAction is what a watched session's owning goroutine receives when the
+// listener honors a CoA-Request or Disconnect-Request against it.
+type coaAction struct {
+	disconnect bool
+	attrs      []Attribute // CoA attribute changes; unset when disconnect is true
+}
+
+// CoAListener binds UDP/3799 and plays both sides of RFC 5176: it receives
+// CoA-Request / Disconnect-Request packets from a policy server (so this
+// tool can impersonate a NAS under test) and, via SendCoARequest /
+// SendDisconnectRequest, sends them against a NAS under test.
+type CoAListener struct {
+	Config   *Config
+	Sessions *SessionRegistry
+	Rng      RNG
+	conn     *net.UDPConn
+
+	mu       sync.Mutex
+	watchers map[string]chan coaAction // keyed by AccountingSession.UniqueID
+}
+
+// NewCoAListener creates a listener that resolves sessions against registry,
+// sampling the synthesized Admin-Reset Accounting-Stop's final traffic
+// counters from rng.
+func NewCoAListener(config *Config, registry *SessionRegistry, rng RNG) *CoAListener {
+	return &CoAListener{
+		Config:   config,
+		Sessions: registry,
+		Rng:      rng,
+		watchers: make(map[string]chan coaAction),
+	}
+}
+
+// Watch registers session as owned by the caller's goroutine: a CoA or
+// Disconnect honored against it is delivered on the returned channel instead
+// of being applied by the listener directly, so the owning goroutine (e.g.
+// keepUserOnline) can stop its own ticker before sending the Admin-Reset
+// Accounting-Stop. Call the returned stop func once the goroutine is done
+// with the session, typically in a defer right after Watch.
+func (l *CoAListener) Watch(session *AccountingSession) (<-chan coaAction, func()) {
+	ch := make(chan coaAction, 1)
+	l.mu.Lock()
+	l.watchers[session.UniqueID] = ch
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.watchers, session.UniqueID)
+		l.mu.Unlock()
+	}
+}
+
+// ListenAndServe binds UDP/3799 and serves requests until the listener is
+// closed or a read error occurs.
+func (l *CoAListener) ListenAndServe() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: coaListenPort})
+	if err != nil {
+		return fmt.Errorf("bind CoA listener: %w", err)
+	}
+	l.conn = conn
+	defer conn.Close()
+
+	log.Printf("CoA listener: bound %s", conn.LocalAddr())
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, remote, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return fmt.Errorf("CoA listener read: %w", err)
+		}
+		go l.handle(conn, remote, append([]byte(nil), buf[:n]...))
+	}
+}
+
+// Close stops the listener.
+func (l *CoAListener) Close() error {
+	if l.conn == nil {
+		return nil
+	}
+	return l.conn.Close()
+}
+
+func (l *CoAListener) handle(conn *net.UDPConn, remote *net.UDPAddr, data []byte) {
+	req, err := DecodePacket(data)
+	if err != nil {
+		log.Printf("CoA listener: malformed packet from %s: %v", remote, err)
+		return
+	}
+	if req.Code != CodeCoARequest && req.Code != CodeDisconnectRequest {
+		log.Printf("CoA listener: unexpected code %d from %s", req.Code, remote)
+		return
+	}
+
+	secret := []byte(l.Config.RadiusSecret)
+	wantAuth := accountingRequestAuthenticator(req.Code, req.Identifier, encodeAttrs(req.Attributes), secret)
+	if wantAuth != req.Authenticator {
+		log.Printf("CoA listener: Request Authenticator validation failed from %s", remote)
+		return
+	}
+
+	acctSessionID, _ := req.GetString(AttrAcctSessionID)
+	username, _ := req.GetString(AttrUserName)
+	framedIP := ""
+	if ip, ok := req.DecodeIP(AttrFramedIPAddress); ok {
+		framedIP = ip.String()
+	}
+
+	session, found := l.Sessions.Find(acctSessionID, username, framedIP)
+
+	var replyCode Code
+	switch {
+	case !found:
+		replyCode = nakFor(req.Code)
+	case req.Code == CodeDisconnectRequest:
+		l.disconnect(session)
+		replyCode = CodeDisconnectACK
+	default:
+		l.coa(session, req.Attributes)
+		replyCode = CodeCoAACK
+	}
+
+	l.reply(conn, remote, req, replyCode)
+}
+
+// disconnect honors a Disconnect-Request against session: if a goroutine is
+// watching it, the action is handed off so that goroutine can stop its own
+// ticker before sending the Admin-Reset Stop; otherwise the listener
+// terminates the session itself.
+func (l *CoAListener) disconnect(session *AccountingSession) {
+	if l.deliver(session, coaAction{disconnect: true}) {
+		return
+	}
+	l.terminateSession(session)
+}
+
+// coa honors a CoA-Request against session: if a goroutine is watching it,
+// the attribute changes are handed off so that goroutine applies them
+// itself; otherwise the listener applies them directly.
+func (l *CoAListener) coa(session *AccountingSession, attrs []Attribute) {
+	if l.deliver(session, coaAction{attrs: attrs}) {
+		return
+	}
+	applyCoAAttributes(session, attrs)
+}
+
+// deliver sends action to session's watcher channel, if any, and reports
+// whether a watcher was found.
+func (l *CoAListener) deliver(session *AccountingSession, action coaAction) bool {
+	l.mu.Lock()
+	ch, ok := l.watchers[session.UniqueID]
+	l.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	select {
+	case ch <- action:
+	default:
+		log.Printf("CoA listener: watcher for %s did not keep up, action dropped", session.Username)
+	}
+	return true
+}
+
+func nakFor(code Code) Code {
+	if code == CodeDisconnectRequest {
+		return CodeDisconnectNAK
+	}
+	return CodeCoANAK
+}
+
+// terminateSession synthesizes an Accounting-Stop with Acct-Terminate-Cause
+// = Admin-Reset and drops the session from the registry, as RFC 5176 §3.2
+// requires when a Disconnect-Request is honored. It's only used for sessions
+// nothing is watching (see handle); a watched session disconnects itself so
+// its own goroutine can stop its ticker first.
+func (l *CoAListener) terminateSession(session *AccountingSession) {
+	session.Duration = int(time.Since(session.StartTime).Seconds())
+	if err := SendAccountingStopWithCause(l.Config, session, 0, l.Rng, "Admin-Reset"); err != nil {
+		log.Printf("CoA listener: Admin-Reset Accounting-Stop failed for %s: %v", session.Username, err)
+	}
+	l.Sessions.Remove(session.UniqueID)
+}
+
+// applyCoAAttributes merges the changed attributes from a CoA-Request onto
+// the tracked session's framed state.
+func applyCoAAttributes(session *AccountingSession, attrs []Attribute) {
+	for _, a := range attrs {
+		if a.Type == AttrFramedIPAddress && len(a.Value) == 4 {
+			session.FramedIPAddress = net.IP(a.Value).String()
+		}
+	}
+}
+
+func (l *CoAListener) reply(conn *net.UDPConn, remote *net.UDPAddr, req *Packet, code Code) {
+	secret := []byte(l.Config.RadiusSecret)
+	resp := &Packet{Code: code, Identifier: req.Identifier, Secret: secret}
+	resp.Authenticator = responseAuthenticator(code, req.Identifier, encodeAttrs(resp.Attributes), req.Authenticator, secret)
+
+	wire, err := resp.Encode()
+	if err != nil {
+		log.Printf("CoA listener: encode reply failed: %v", err)
+		return
+	}
+	if _, err := conn.WriteToUDP(wire, remote); err != nil {
+		log.Printf("CoA listener: write reply failed: %v", err)
+	}
+}
+
+// SendCoARequest sends a CoA-Request carrying attrs to the NAS at addr and
+// returns the decoded CoA-ACK/NAK.
+func SendCoARequest(config *Config, addr string, attrs []Attribute) (*Packet, error) {
+	return sendCoA(config, addr, CodeCoARequest, attrs)
+}
+
+// SendDisconnectRequest sends a Disconnect-Request carrying attrs to the NAS
+// at addr and returns the decoded Disconnect-ACK/NAK.
+func SendDisconnectRequest(config *Config, addr string, attrs []Attribute) (*Packet, error) {
+	return sendCoA(config, addr, CodeDisconnectRequest, attrs)
+}
+
+func sendCoA(config *Config, addr string, code Code, attrs []Attribute) (*Packet, error) {
+	identifier := nextIdentifier()
+	secret := []byte(config.RadiusSecret)
+	authenticator := accountingRequestAuthenticator(code, identifier, encodeAttrs(attrs), secret)
+
+	req := &Packet{
+		Code:          code,
+		Identifier:    identifier,
+		Authenticator: authenticator,
+		Attributes:    attrs,
+		Secret:        secret,
+	}
+
+	resp, _, err := transport(config).Exchange(addr, req)
+	if err != nil {
+		return nil, fmt.Errorf("CoA exchange failed: %w", err)
+	}
+
+	want := responseAuthenticator(resp.Code, resp.Identifier, encodeAttrs(resp.Attributes), authenticator, secret)
+	if want != resp.Authenticator {
+		return nil, fmt.Errorf("response authenticator validation failed")
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cmd/coatest exercises RFC 5176 Change-of-Authorization and
+// Disconnect-Request handling end-to-end: it starts its own accounting
+// sessions (playing the NAS, same as simulate_online.go), runs a CoAListener
+// in-process to honor CoA/Disconnect-Request against them, and then drives
+// that listener as a policy server would via SendCoARequest/
+// SendDisconnectRequest - so no external NAS is required to test the
+// round trip. Point -coa-addr at a real NAS instead to test one.
+func main() {
+	numSessions := flag.Int("sessions", 20, "Number of live accounting sessions to start before running the CoA scenario")
+	concurrency := flag.Int("concurrent", 5, "Number of sessions to start/act on concurrently")
+	maxUsers := flag.Int("users", 50, "Maximum number of users from database")
+	seed := flag.Int64("seed", 0, "RNG seed for reproducible session/disconnect selection (default: random)")
+	coaAddr := flag.String("coa-addr", "", "NAS CoA/Disconnect listener address (host:port); defaults to the local CoAListener on 127.0.0.1:3799")
+	scenario := flag.String("scenario", "timeout", `CoA scenario to run: "timeout" (push a new Session-Timeout via CoA, verify CoA-ACK) or "disconnect" (Disconnect-Request -disconnect-pct of sessions, verify the listener tears each one down with an Admin-Reset Accounting-Stop)`)
+	sessionTimeout := flag.Int("session-timeout", 1800, "-scenario=timeout only: new Session-Timeout value to push via CoA")
+	disconnectPct := flag.Int("disconnect-pct", 50, "-scenario=disconnect only: percentage of sessions to disconnect")
+	wait := flag.Duration("wait", 5*time.Second, "how long to let sessions sit active before running the scenario")
+	verifyTimeout := flag.Duration("verify-timeout", 3*time.Second, "-scenario=disconnect only: how long to wait for the session to disappear from the registry before giving up on verifying its Accounting-Stop")
+	flag.Parse()
+
+	log.Println("========================================")
+	log.Println("RADIUS CoA/Disconnect Test")
+	log.Println("========================================")
+
+	rng := NewSyncRand(NewSeededRand(*seed))
+	config := LoadConfig()
+
+	addr := *coaAddr
+	if addr == "" {
+		addr = fmt.Sprintf("127.0.0.1:%d", coaListenPort)
+	}
+
+	db, err := ConnectDB(config)
+	if err != nil {
+		log.Fatalf("Database connection failed: %v", err)
+	}
+	defer db.Close()
+
+	users, err := FetchActiveUsers(db, *maxUsers)
+	if err != nil {
+		log.Fatalf("Failed to fetch users: %v", err)
+	}
+	if len(users) == 0 {
+		log.Fatal("No active users found in database")
+	}
+
+	coaListener := NewCoAListener(config, activeSessions, rng)
+	go func() {
+		if err := coaListener.ListenAndServe(); err != nil {
+			log.Printf("CoA listener stopped: %v", err)
+		}
+	}()
+	defer coaListener.Close()
+
+	log.Printf("Starting %d live sessions (%d concurrent)...", *numSessions, *concurrency)
+	sessions := startLiveSessions(config, users, rng, *numSessions, *concurrency)
+	if len(sessions) == 0 {
+		log.Fatal("No sessions could be started; nothing to test")
+	}
+	log.Printf("  - %d/%d sessions online", len(sessions), *numSessions)
+
+	log.Printf("Waiting %s before running the %q scenario...", *wait, *scenario)
+	time.Sleep(*wait)
+
+	switch *scenario {
+	case "timeout":
+		runTimeoutScenario(config, addr, sessions, *concurrency, uint32(*sessionTimeout))
+	case "disconnect":
+		runDisconnectScenario(config, addr, sessions, rng, *concurrency, *disconnectPct, *verifyTimeout)
+	default:
+		log.Fatalf("unknown -scenario %q (want \"timeout\" or \"disconnect\")", *scenario)
+	}
+}
+
+// startLiveSessions sends an Accounting-Start for numSessions users
+// (round-robin, up to concurrency at a time) and registers each in
+// activeSessions so the CoAListener can resolve CoA/Disconnect-Request
+// against it. Sessions whose Accounting-Start failed are dropped.
+func startLiveSessions(config *Config, users []RadiusUser, rng RNG, numSessions, concurrency int) []*AccountingSession {
+	var mu sync.Mutex
+	var sessions []*AccountingSession
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+
+	for i := 0; i < numSessions; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			user := users[i%len(users)]
+			now := time.Now()
+			session := &AccountingSession{
+				Username:        user.Username,
+				SessionID:       fmt.Sprintf("coatest-session-%d-%d", now.Unix(), i),
+				UniqueID:        fmt.Sprintf("coatest-%d-%d", now.UnixNano(), i),
+				FramedIPAddress: GenerateRandomIP(rng),
+				StartTime:       now,
+				LastUpdate:      now,
+				Profile:         pickTrafficProfile(rng),
+			}
+			nasPort := 40000 + i
+
+			activeSessions.Register(session)
+			if err := SendAccountingStart(config, session, nasPort); err != nil {
+				log.Printf("  ✗ Acct-Start failed for %s: %v", user.Username, err)
+				activeSessions.Remove(session.UniqueID)
+				return
+			}
+
+			mu.Lock()
+			sessions = append(sessions, session)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	return sessions
+}
+
+// runTimeoutScenario sends a CoA-Request pushing sessionTimeout as each
+// session's new Session-Timeout and verifies a CoA-ACK comes back; a NAK is
+// reported with its decoded Error-Cause.
+func runTimeoutScenario(config *Config, addr string, sessions []*AccountingSession, concurrency int, sessionTimeout uint32) {
+	var acked, naked, failed int64
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, session := range sessions {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(session *AccountingSession) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			p := &Packet{}
+			p.AttrString(AttrAcctSessionID, session.SessionID)
+			p.AttrString(AttrUserName, session.Username)
+			p.AttrUint32(AttrSessionTimeout, sessionTimeout)
+
+			resp, err := SendCoARequest(config, addr, p.Attributes)
+			switch {
+			case err != nil:
+				log.Printf("  ✗ %s: CoA-Request failed: %v", session.Username, err)
+				atomic.AddInt64(&failed, 1)
+			case resp.Code == CodeCoAACK:
+				log.Printf("  ✓ %s: CoA-ACK (Session-Timeout=%d)", session.Username, sessionTimeout)
+				atomic.AddInt64(&acked, 1)
+			default:
+				log.Printf("  ✗ %s: CoA-NAK (%s)", session.Username, decodeErrorCause(resp))
+				atomic.AddInt64(&naked, 1)
+			}
+		}(session)
+	}
+	wg.Wait()
+
+	fmt.Println("\n========================================")
+	fmt.Println("CoA Session-Timeout Scenario Summary")
+	fmt.Println("========================================")
+	fmt.Printf("Sessions:   %d\n", len(sessions))
+	fmt.Printf("✓ ACK:      %d\n", acked)
+	fmt.Printf("✗ NAK:      %d\n", naked)
+	fmt.Printf("✗ Failed:   %d\n", failed)
+	fmt.Println("========================================")
+}
+
+// runDisconnectScenario Disconnect-Requests roughly disconnectPct% of
+// sessions, verifies the Disconnect-ACK, and then polls activeSessions to
+// confirm the CoAListener (see CoAListener.terminateSession) removed the
+// session after sending its Admin-Reset Accounting-Stop. terminateSession
+// removes the session even when that Stop fails (the failure is only
+// logged), so this check does not show that the server received the Stop.
+func runDisconnectScenario(config *Config, addr string, sessions []*AccountingSession, rng RNG, concurrency, disconnectPct int, verifyTimeout time.Duration) {
+	var picked []*AccountingSession
+	for _, session := range sessions {
+		if rng.Intn(100) < disconnectPct {
+			picked = append(picked, session)
+		}
+	}
+	log.Printf("Disconnecting %d/%d sessions (~%d%%)...", len(picked), len(sessions), disconnectPct)
+
+	var acked, naked, failed, verified int64
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, session := range picked {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(session *AccountingSession) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			p := &Packet{}
+			p.AttrString(AttrAcctSessionID, session.SessionID)
+			p.AttrString(AttrUserName, session.Username)
+
+			resp, err := SendDisconnectRequest(config, addr, p.Attributes)
+			switch {
+			case err != nil:
+				log.Printf("  ✗ %s: Disconnect-Request failed: %v", session.Username, err)
+				atomic.AddInt64(&failed, 1)
+				return
+			case resp.Code != CodeDisconnectACK:
+				log.Printf("  ✗ %s: Disconnect-NAK (%s)", session.Username, decodeErrorCause(resp))
+				atomic.AddInt64(&naked, 1)
+				return
+			}
+			log.Printf("  ✓ %s: Disconnect-ACK", session.Username)
+			atomic.AddInt64(&acked, 1)
+
+			if waitForAccountingStop(session, verifyTimeout) {
+				atomic.AddInt64(&verified, 1)
+			} else {
+				log.Printf("  ✗ %s: no Accounting-Stop observed within %s", session.Username, verifyTimeout)
+			}
+		}(session)
+	}
+	wg.Wait()
+
+	fmt.Println("\n========================================")
+	fmt.Println("CoA Disconnect Scenario Summary")
+	fmt.Println("========================================")
+	fmt.Printf("Disconnected:        %d/%d\n", len(picked), len(sessions))
+	fmt.Printf("✓ ACK:               %d\n", acked)
+	fmt.Printf("✗ NAK:               %d\n", naked)
+	fmt.Printf("✗ Failed:            %d\n", failed)
+	fmt.Printf("✓ Accounting-Stop seen: %d\n", verified)
+	fmt.Println("========================================")
+}
+
+// waitForAccountingStop polls activeSessions until session.UniqueID is gone
+// - meaning CoAListener.terminateSession ran its Accounting-Stop and
+// deregistered it - or verifyTimeout elapses.
+func waitForAccountingStop(session *AccountingSession, verifyTimeout time.Duration) bool {
+	deadline := time.Now().Add(verifyTimeout)
+	for time.Now().Before(deadline) {
+		if _, found := activeSessions.Find(session.SessionID, "", ""); !found {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+// decodeErrorCause reads resp's Error-Cause attribute, if present, into a
+// human-readable label for a CoA/Disconnect-NAK log line.
+func decodeErrorCause(resp *Packet) string {
+	cause, ok := resp.DecodeFour(AttrErrorCause)
+	if !ok {
+		return "no Error-Cause attribute"
+	}
+	return fmt.Sprintf("Error-Cause %d: %s", cause, errorCauseLabel(cause))
+}
+
+func errorCauseLabel(cause uint32) string {
+	switch cause {
+	case ErrorCauseResidualSessionContext:
+		return "Residual Session Context Removed"
+	case ErrorCauseInvalidEAPPacket:
+		return "Invalid EAP Packet"
+	case ErrorCauseUnsupportedAttribute:
+		return "Unsupported Attribute"
+	case ErrorCauseMissingAttribute:
+		return "Missing Attribute"
+	case ErrorCauseNASIdentificationMismatch:
+		return "NAS Identification Mismatch"
+	case ErrorCauseInvalidRequest:
+		return "Invalid Request"
+	case ErrorCauseUnsupportedService:
+		return "Unsupported Service"
+	case ErrorCauseUnsupportedExtension:
+		return "Unsupported Extension"
+	case ErrorCauseAdministrativelyProhibited:
+		return "Administratively Prohibited"
+	case ErrorCauseRequestNotRoutable:
+		return "Request Not Routable"
+	case ErrorCauseSessionContextNotFound:
+		return "Session Context Not Found"
+	case ErrorCauseSessionContextNotRemovable:
+		return "Session Context Not Removable"
+	case ErrorCauseOtherProxyProcessingError:
+		return "Other Proxy Processing Error"
+	case ErrorCauseResourcesUnavailable:
+		return "Resources Unavailable"
+	case ErrorCauseRequestInitiated:
+		return "Request Initiated"
+	case ErrorCauseMultipleSessionsMatch:
+		return "Multiple Sessions Match"
+	default:
+		return "unknown"
+	}
+}
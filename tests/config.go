@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -19,6 +21,27 @@ type Config struct {
 	RadiusSecret   string
 	NASIPAddress   string
 	NASPortID      string
+
+	// Native client tuning
+	RadiusTimeout time.Duration
+	RadiusRetries int
+	AuthMethod    AuthMethod
+	Transport     TransportMode
+
+	// RadSec (RFC 6614) TLS options; only consulted when Transport == TransportTLS.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSCAFile       string
+	TLSServerName   string
+	TLSPinnedSHA256 string
+
+	// Accounting rate limits; 0 means unlimited. GlobalPPS/GlobalBurst bound
+	// the shared limiter every goroutine waits on, PerUserPPS/PerUserBurst
+	// size the per-session limiter keepUserOnline creates for itself.
+	GlobalPPS    int
+	GlobalBurst  int
+	PerUserPPS   int
+	PerUserBurst int
 }
 
 func LoadConfig() *Config {
@@ -37,6 +60,22 @@ func LoadConfig() *Config {
 		RadiusSecret:   getEnv("RADIUS_SECRET", "testing123"),
 		NASIPAddress:   getEnv("NAS_IP_ADDRESS", "192.168.1.10"),
 		NASPortID:      getEnv("NAS_PORT_ID", "eth1"),
+
+		RadiusTimeout: time.Duration(getEnvInt("RADIUS_TIMEOUT_MS", 3000)) * time.Millisecond,
+		RadiusRetries: getEnvInt("RADIUS_RETRIES", 3),
+		AuthMethod:    AuthMethod(getEnv("AUTH_METHOD", string(AuthPAP))),
+		Transport:     TransportMode(getEnv("RADIUS_TRANSPORT", string(TransportUDP))),
+
+		TLSCertFile:     getEnv("RADIUS_TLS_CERT_FILE", ""),
+		TLSKeyFile:      getEnv("RADIUS_TLS_KEY_FILE", ""),
+		TLSCAFile:       getEnv("RADIUS_TLS_CA_FILE", ""),
+		TLSServerName:   getEnv("RADIUS_TLS_SERVER_NAME", ""),
+		TLSPinnedSHA256: getEnv("RADIUS_TLS_PINNED_SHA256", ""),
+
+		GlobalPPS:    getEnvInt("RADIUS_GLOBAL_PPS", 0),
+		GlobalBurst:  getEnvInt("RADIUS_GLOBAL_BURST", 1),
+		PerUserPPS:   getEnvInt("RADIUS_PER_USER_PPS", 0),
+		PerUserBurst: getEnvInt("RADIUS_PER_USER_BURST", 1),
 	}
 }
 
@@ -46,3 +85,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
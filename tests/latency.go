@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// latencyLowMicros/latencyHighMicros/latencySigFigs bound every HDR
+// histogram a LatencyRecorder creates: 1µs to 60s at 3 significant digits,
+// as requested for CI-gradeable percentile reporting.
+const (
+	latencyLowMicros  = 1
+	latencyHighMicros = 60 * time.Second / time.Microsecond
+	latencySigFigs    = 3
+)
+
+// LatencyRecorder tracks per-request-type RTT in HDR histograms instead of
+// raw sample slices, so memory stays bounded (and precision guaranteed to 3
+// significant digits) no matter how many requests a run sends. "auth" is
+// Access-Request RTT; accounting types are "acct-" plus whatever
+// acctStatusTypeLabel returns ("acct-start", "acct-interim", "acct-stop").
+type LatencyRecorder struct {
+	mu     sync.Mutex
+	byType map[string]*hdrhistogram.Histogram
+}
+
+// NewLatencyRecorder creates an empty recorder.
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{byType: make(map[string]*hdrhistogram.Histogram)}
+}
+
+// Record adds one RTT sample under typ, creating that type's histogram on
+// first use. A value outside the histogram's [1µs, 60s] range is clamped
+// rather than dropped, so one slow outlier can't silently vanish from p100.
+func (r *LatencyRecorder) Record(typ string, rtt time.Duration) {
+	micros := rtt.Microseconds()
+	if micros < latencyLowMicros {
+		micros = latencyLowMicros
+	}
+	if micros > int64(latencyHighMicros) {
+		micros = int64(latencyHighMicros)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.byType[typ]
+	if !ok {
+		h = hdrhistogram.New(latencyLowMicros, int64(latencyHighMicros), latencySigFigs)
+		r.byType[typ] = h
+	}
+	h.RecordValue(micros)
+}
+
+// types returns every recorded type, sorted for stable report ordering.
+func (r *LatencyRecorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.byType))
+	for typ := range r.byType {
+		out = append(out, typ)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// quantile returns the value at percentile q (0-100) for typ, or 0 if
+// nothing has been recorded under it.
+func (r *LatencyRecorder) quantile(typ string, q float64) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.byType[typ]
+	if !ok {
+		return 0
+	}
+	return time.Duration(h.ValueAtQuantile(q)) * time.Microsecond
+}
+
+// PrintSummary prints p50/p90/p99/p99.9/max and a compact ASCII histogram
+// for every request type with at least one recorded sample.
+func (r *LatencyRecorder) PrintSummary() {
+	fmt.Println("\n========================================")
+	fmt.Println("Latency Summary (HDR histogram, 3 significant digits)")
+	fmt.Println("========================================")
+
+	for _, typ := range r.types() {
+		r.mu.Lock()
+		h := r.byType[typ]
+		r.mu.Unlock()
+		if h.TotalCount() == 0 {
+			continue
+		}
+
+		fmt.Printf("\n--- %s (%d samples) ---\n", typ, h.TotalCount())
+		for _, p := range []float64{50, 90, 99, 99.9} {
+			fmt.Printf("  p%-5g %s\n", p, (time.Duration(h.ValueAtQuantile(p)) * time.Microsecond).Round(time.Microsecond))
+		}
+		fmt.Printf("  max:   %s\n", (time.Duration(h.Max()) * time.Microsecond).Round(time.Microsecond))
+
+		fmt.Println("  Histogram:")
+		printHDRHistogram(h)
+	}
+	fmt.Println("========================================")
+}
+
+// printHDRHistogram buckets h's recorded range into 10 equal-width buckets
+// and prints a bar per bucket, matching printHistogram's hey/boom style.
+func printHDRHistogram(h *hdrhistogram.Histogram) {
+	const buckets = 10
+	lowest, highest := h.Min(), h.Max()
+	width := highest - lowest
+	if width <= 0 {
+		fmt.Printf("    %s [%d]\t%s\n",
+			(time.Duration(lowest) * time.Microsecond).Round(time.Microsecond),
+			h.TotalCount(), bar(int(h.TotalCount()), int(h.TotalCount())))
+		return
+	}
+
+	counts := make([]int, buckets)
+	for _, b := range h.Distribution() {
+		if b.Count == 0 {
+			continue
+		}
+		idx := int(float64(b.From-lowest) / float64(width) * buckets)
+		if idx >= buckets {
+			idx = buckets - 1
+		}
+		counts[idx] += int(b.Count)
+	}
+
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	for i, c := range counts {
+		lower := time.Duration(lowest+int64(float64(width)*float64(i)/buckets)) * time.Microsecond
+		fmt.Printf("    %s [%d]\t%s\n", lower.Round(time.Microsecond), c, bar(c, max))
+	}
+}
+
+// histogramDump is the JSON shape DumpFile writes: one HDR snapshot per
+// request type, exported via hdrhistogram's own encoding so a later tool can
+// Import and Merge snapshots from multiple hosts.
+type histogramDump struct {
+	Type     string                 `json:"type"`
+	Snapshot *hdrhistogram.Snapshot `json:"snapshot"`
+}
+
+// DumpFile writes every type's raw HDR snapshot to path as JSON, for
+// merging across multi-host runs later.
+func (r *LatencyRecorder) DumpFile(path string) error {
+	r.mu.Lock()
+	dumps := make([]histogramDump, 0, len(r.byType))
+	for typ, h := range r.byType {
+		dumps = append(dumps, histogramDump{Type: typ, Snapshot: h.Export()})
+	}
+	r.mu.Unlock()
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].Type < dumps[j].Type })
+
+	data, err := json.MarshalIndent(dumps, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// hdrSink is a MetricsSink that feeds every auth/accounting RTT into a
+// LatencyRecorder, so -slo gating and the HDR summary see the same attempts
+// Prometheus/StatsD do.
+type hdrSink struct {
+	recorder *LatencyRecorder
+}
+
+func (s *hdrSink) AuthResult(_ string, rtt time.Duration) {
+	s.recorder.Record("auth", rtt)
+}
+
+func (s *hdrSink) AcctResult(kind string, rtt time.Duration) {
+	s.recorder.Record("acct-"+kind, rtt)
+}
+
+func (s *hdrSink) Close() error { return nil }
+
+// SLO is a parsed -slo flag value, e.g. "p99=200ms" or "acct-stop:p99.9=1s".
+type SLO struct {
+	Type       string // "auth" if unspecified
+	Percentile float64
+	Max        time.Duration
+}
+
+// ParseSLO parses a -slo flag value of the form "[type:]pNN=duration", e.g.
+// "p99=200ms" (applies to auth) or "acct-stop:p99.9=1s".
+func ParseSLO(spec string) (SLO, error) {
+	slo := SLO{Type: "auth"}
+
+	rest := spec
+	if idx := strings.LastIndexByte(spec, ':'); idx >= 0 {
+		slo.Type = spec[:idx]
+		rest = spec[idx+1:]
+	}
+
+	var pctStr, durStr string
+	eq := strings.LastIndexByte(rest, '=')
+	if eq < 0 || len(rest) < 2 || rest[0] != 'p' {
+		return SLO{}, fmt.Errorf("malformed -slo %q, want \"[type:]pNN=duration\"", spec)
+	}
+	pctStr, durStr = rest[1:eq], rest[eq+1:]
+
+	if _, err := fmt.Sscanf(pctStr, "%g", &slo.Percentile); err != nil {
+		return SLO{}, fmt.Errorf("malformed -slo percentile %q: %w", pctStr, err)
+	}
+	dur, err := time.ParseDuration(durStr)
+	if err != nil {
+		return SLO{}, fmt.Errorf("malformed -slo duration %q: %w", durStr, err)
+	}
+	slo.Max = dur
+
+	return slo, nil
+}
+
+// Check reports whether r breached slo, logging the observed value either way.
+func (r *LatencyRecorder) Check(slo SLO) bool {
+	got := r.quantile(slo.Type, slo.Percentile)
+	breached := got > slo.Max
+	status := "OK"
+	if breached {
+		status = "BREACHED"
+	}
+	fmt.Printf("SLO %s p%g=%s: observed %s [%s]\n", slo.Type, slo.Percentile, slo.Max, got, status)
+	return breached
+}
+
+// finishRun prints recorder's HDR summary, dumps its raw histograms to
+// dumpFile if set, and gates on sloSpec if set, exiting 1 on breach. Every
+// load_test.go workload calls this once it has finished sending traffic.
+func finishRun(recorder *LatencyRecorder, sloSpec, dumpFile string) {
+	recorder.PrintSummary()
+
+	if dumpFile != "" {
+		if err := recorder.DumpFile(dumpFile); err != nil {
+			log.Printf("histogram-dump: %v", err)
+		} else {
+			log.Printf("Wrote raw latency histograms to %s", dumpFile)
+		}
+	}
+
+	if sloSpec == "" {
+		return
+	}
+	slo, err := ParseSLO(sloSpec)
+	if err != nil {
+		log.Fatalf("slo: %v", err)
+	}
+	if recorder.Check(slo) {
+		os.Exit(1)
+	}
+}
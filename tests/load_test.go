@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -14,23 +18,46 @@ func main() {
 	totalSessions := flag.Int("sessions", 100, "Total number of sessions to create")
 	concurrency := flag.Int("concurrent", 10, "Number of concurrent sessions")
 	maxUsers := flag.Int("users", 50, "Maximum number of users from database")
+	seed := flag.Int64("seed", 0, "RNG seed for reproducible traffic (default: random)")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus /metrics on (e.g. :9090); disabled if empty")
+	statsdAddr := flag.String("statsd-addr", "", "Address of a StatsD/dogstatsd server to mirror auth/accounting metrics to (e.g. 127.0.0.1:8125); disabled if empty")
+	scenarioFile := flag.String("scenario", "", "Run a weighted multi-flow scenario from this YAML file instead of the flat -sessions/-concurrent workload")
+	maxInFlight := flag.Int("max-inflight", 2000, "-scenario only: cap on concurrently running flows before new arrivals are dropped")
+	lifecycle := flag.Bool("lifecycle", false, "Run a full session-lifecycle simulation (Access-Request -> Accounting-Start -> periodic Interim-Update -> Accounting-Stop) instead of the flat -sessions/-concurrent workload")
+	simDuration := flag.Duration("duration", 0, "-lifecycle only: total session lifetime before Accounting-Stop; 0 runs until Ctrl+C")
+	interimInterval := flag.Duration("interim-interval", 60*time.Second, "-lifecycle only: how often to send Accounting-Interim-Update")
+	bandwidthModel := flag.String("bandwidth-model", "constant", "-lifecycle only: traffic shape for simulated byte counters: constant, bursty, or diurnal")
+	sloSpec := flag.String("slo", "", `SLO to gate on, e.g. "p99=200ms" (auth) or "acct-stop:p99.9=1s"; exits non-zero on breach`)
+	histogramDumpFile := flag.String("histogram-dump", "", "Dump raw per-type HDR latency histograms to this file as JSON, for merging across multi-host runs; disabled if empty")
 	flag.Parse()
 
 	log.Println("========================================")
 	log.Println("RADIUS Load Test")
 	log.Println("========================================")
 
-	// Verify radclient is installed
-	if err := VerifyRadclientInstalled(); err != nil {
-		log.Fatalf("Error: %v", err)
+	if *metricsAddr != "" {
+		log.Printf("Serving Prometheus metrics on %s/metrics", *metricsAddr)
+		go func() {
+			if err := ServeMetrics(*metricsAddr); err != nil {
+				log.Printf("metrics server stopped: %v", err)
+			}
+		}()
+	}
+	if *statsdAddr != "" {
+		if err := EnableStatsD(*statsdAddr); err != nil {
+			log.Fatalf("StatsD: %v", err)
+		}
+		log.Printf("Mirroring metrics to StatsD at %s", *statsdAddr)
 	}
 
+	latencyRecorder := NewLatencyRecorder()
+	metricsSinks = append(metricsSinks, &hdrSink{recorder: latencyRecorder})
+
+	rng := NewSyncRand(NewSeededRand(*seed))
+
 	// Load configuration
 	config := LoadConfig()
 	log.Printf("RADIUS Server: %s", config.RadiusServer)
-	log.Printf("Configuration:")
-	log.Printf("  - Total Sessions: %d", *totalSessions)
-	log.Printf("  - Concurrent: %d", *concurrency)
 
 	// Connect to database
 	db, err := ConnectDB(config)
@@ -51,6 +78,22 @@ func main() {
 
 	log.Printf("  - Users: %d\n", len(users))
 
+	if *scenarioFile != "" {
+		runScenario(config, users, rng, *scenarioFile, *maxInFlight)
+		finishRun(latencyRecorder, *sloSpec, *histogramDumpFile)
+		return
+	}
+
+	if *lifecycle {
+		runLifecycleSimulation(config, users, rng, *totalSessions, *simDuration, *interimInterval, *bandwidthModel)
+		finishRun(latencyRecorder, *sloSpec, *histogramDumpFile)
+		return
+	}
+
+	log.Printf("Configuration:")
+	log.Printf("  - Total Sessions: %d", *totalSessions)
+	log.Printf("  - Concurrent: %d", *concurrency)
+
 	log.Println("\n========================================")
 	log.Println("Starting load test...")
 	log.Println("========================================")
@@ -75,27 +118,19 @@ func main() {
 			user := users[sessionNum%len(users)]
 
 			// Authenticate first
-			authResult, err := TestAuthentication(config, user.Username, user.Password)
+			authResult, err := TestAuthenticationMethod(config, user.Username, user.Password, config.AuthMethod)
 			if err != nil || !authResult.Success {
 				atomic.AddInt64(&failCount, 1)
-				if sessionNum%10 == 0 {
-					fmt.Printf("\rProgress: %d/%d sessions (%.0f%%)", sessionNum+1, *totalSessions, float64(sessionNum+1)/float64(*totalSessions)*100)
-				}
 				return
 			}
 
 			// Create accounting session
-			_, err = CreateAccountingSession(config, user.Username, 20000+sessionNum)
+			_, err = CreateAccountingSession(config, user.Username, 20000+sessionNum, rng)
 			if err != nil {
 				atomic.AddInt64(&failCount, 1)
 			} else {
 				atomic.AddInt64(&successCount, 1)
 			}
-
-			// Progress indicator
-			if sessionNum%10 == 0 {
-				fmt.Printf("\rProgress: %d/%d sessions (%.0f%%)", sessionNum+1, *totalSessions, float64(sessionNum+1)/float64(*totalSessions)*100)
-			}
 		}(i)
 
 		// Small delay between launches to avoid overwhelming
@@ -117,6 +152,108 @@ func main() {
 	fmt.Printf("Success Rate:       %.2f%%\n", float64(successCount)/float64(*totalSessions)*100)
 	fmt.Printf("Duration:           %s\n", duration.Round(time.Millisecond))
 	fmt.Printf("Sessions/sec:       %.2f\n", float64(*totalSessions)/duration.Seconds())
-	fmt.Printf("Avg Session Time:   %s\n", (duration / time.Duration(*totalSessions)).Round(time.Millisecond))
 	fmt.Println("========================================")
+
+	finishRun(latencyRecorder, *sloSpec, *histogramDumpFile)
+}
+
+// runScenario loads scenarioPath and drives it to completion (or until
+// Ctrl+C), then prints the PrintAuthResults auth summary and the accounting
+// Reporter's summary.
+func runScenario(config *Config, users []RadiusUser, rng RNG, scenarioPath string, maxInFlight int) {
+	scenario, err := LoadScenario(scenarioPath)
+	if err != nil {
+		log.Fatalf("Scenario: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Println("\nScenario: interrupted, stopping...")
+		cancel()
+	}()
+
+	authSink := newSliceSink()
+	acctReport := NewReporter()
+
+	runner := &ScenarioRunner{
+		Config:      config,
+		Users:       users,
+		Rng:         rng,
+		AuthSink:    authSink,
+		AcctReport:  acctReport,
+		MaxInFlight: maxInFlight,
+	}
+
+	log.Printf("Running scenario %s: %d flow(s), %d phase(s)", scenarioPath, len(scenario.Flows), len(scenario.Phases))
+	start := time.Now()
+	runner.Run(ctx, scenario)
+	log.Printf("Scenario completed in %s", time.Since(start).Round(time.Millisecond))
+
+	acctReport.Close()
+	PrintAuthResults(authSink.snapshot())
+	acctReport.PrintSummary()
+}
+
+// runLifecycleSimulation runs one SessionSimulator per virtual user (round-
+// robin over users up to numUsers), each authenticating once and then
+// accounting for the rest of its session until simDuration elapses (0 runs
+// until Ctrl+C), before printing the same Start/Interim/Stop summary
+// keepUserOnline's Reporter produces.
+func runLifecycleSimulation(config *Config, users []RadiusUser, rng RNG, numUsers int, simDuration, interimInterval time.Duration, bandwidthModel string) {
+	log.Printf("Running %d session-lifecycle simulation(s), interim every %s", numUsers, interimInterval)
+	if simDuration > 0 {
+		log.Printf("Each session ends after %s", simDuration)
+	} else {
+		log.Println("Sessions run until Ctrl+C")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Println("\nLifecycle simulation: interrupted, stopping...")
+		cancel()
+	}()
+
+	reporter := NewReporter()
+	sessions := newShardedSessions()
+
+	var wg sync.WaitGroup
+	for i := 0; i < numUsers; i++ {
+		user := users[i%len(users)]
+		model, err := NewTrafficModel(bandwidthModel, i, "")
+		if err != nil {
+			log.Fatalf("Traffic model: %v", err)
+		}
+
+		sim := &SessionSimulator{
+			Config:          config,
+			Reporter:        reporter,
+			Sessions:        sessions,
+			InterimInterval: interimInterval,
+			Duration:        simDuration,
+		}
+
+		wg.Add(1)
+		go func(userNum int, u RadiusUser, m TrafficModel) {
+			defer wg.Done()
+			if err := sim.Run(ctx, u, 50000+userNum, rng, m); err != nil {
+				log.Printf("  ✗ %v", err)
+			}
+		}(i, user, model)
+
+		time.Sleep(50 * time.Millisecond) // stagger session starts
+	}
+
+	wg.Wait()
+	log.Printf("All sessions stopped; %d still tracked (expect 0)", sessions.Len())
+
+	reporter.Close()
+	reporter.PrintSummary()
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"golang.org/x/time/rate"
+)
+
+// LoadGeneratorConfig sets target throughput, worker pool size, and session
+// lifetime bounds for LoadGenerator.
+type LoadGeneratorConfig struct {
+	AuthPerSec           float64
+	SessionsPerSec       float64
+	InterimUpdatesPerSec float64
+	Workers              int
+	MinSessionLifetime   time.Duration
+	MaxSessionLifetime   time.Duration
+}
+
+// ResultSink receives every completed authentication result. A sliceSink
+// whose snapshot feeds PrintAuthResults is one sink among several a caller
+// can attach (e.g. Metrics already sees
+// every packet; a sink can additionally log or persist per-user outcomes).
+type ResultSink interface {
+	Record(AuthResult)
+}
+
+// sliceSink buffers results in memory for a PrintAuthResults-style summary
+// once a run ends.
+type sliceSink struct {
+	mu      sync.Mutex
+	results []AuthResult
+}
+
+func newSliceSink() *sliceSink { return &sliceSink{} }
+
+func (s *sliceSink) Record(r AuthResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, r)
+}
+
+func (s *sliceSink) snapshot() []AuthResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuthResult(nil), s.results...)
+}
+
+// LoadGenerator drives sustained, rate-limited authentication and
+// accounting traffic across a fixed worker pool. Each target rate gets its
+// own token-bucket limiter so auth, session-start, and interim-update
+// throughput can be tuned independently of each other and of worker count.
+type LoadGenerator struct {
+	Config  *Config
+	LoadCfg LoadGeneratorConfig
+	Rng     RNG
+	Sinks   []ResultSink
+
+	authLimiter    *rate.Limiter
+	sessionLimiter *rate.Limiter
+	interimLimiter *rate.Limiter
+}
+
+// NewLoadGenerator builds a generator whose limiters are sized from
+// loadCfg's target rates with a burst of 1, i.e. strictly paced rather than
+// bursty.
+func NewLoadGenerator(config *Config, loadCfg LoadGeneratorConfig, rng RNG, sinks ...ResultSink) *LoadGenerator {
+	return &LoadGenerator{
+		Config:         config,
+		LoadCfg:        loadCfg,
+		Rng:            rng,
+		Sinks:          sinks,
+		authLimiter:    rate.NewLimiter(rate.Limit(loadCfg.AuthPerSec), 1),
+		sessionLimiter: rate.NewLimiter(rate.Limit(loadCfg.SessionsPerSec), 1),
+		interimLimiter: rate.NewLimiter(rate.Limit(loadCfg.InterimUpdatesPerSec), 1),
+	}
+}
+
+// Run starts LoadCfg.Workers workers, each repeatedly picking a user from
+// users and driving one authentication plus accounting session, until ctx
+// is canceled.
+func (g *LoadGenerator) Run(ctx context.Context, users []RadiusUser) {
+	workers := g.LoadCfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			g.runWorker(ctx, workerID, users)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (g *LoadGenerator) runWorker(ctx context.Context, workerID int, users []RadiusUser) {
+	for i := 0; ; i++ {
+		if err := g.sessionLimiter.Wait(ctx); err != nil {
+			return // ctx canceled
+		}
+		user := users[g.Rng.Intn(len(users))]
+		g.runSession(ctx, workerID*1_000_000+i, user)
+	}
+}
+
+// runSession authenticates user, then - if authentication succeeds - runs a
+// bounded-lifetime accounting session with interim updates paced by
+// interimLimiter.
+func (g *LoadGenerator) runSession(ctx context.Context, nasPort int, user RadiusUser) {
+	start := time.Now()
+
+	if err := g.authLimiter.Wait(ctx); err != nil {
+		return
+	}
+	result, err := TestAuthenticationMethod(g.Config, user.Username, user.Password, g.Config.AuthMethod)
+	if result != nil {
+		g.record(*result)
+	}
+	if err != nil || result == nil || !result.Success {
+		return
+	}
+
+	lifetime := g.sessionLifetime()
+	session, err := g.startSession(user.Username, nasPort, lifetime)
+	if err != nil {
+		log.Printf("loadgen: Acct-Start failed for %s: %v", user.Username, err)
+		return
+	}
+	activeSessions.Register(session)
+	defer activeSessions.Remove(session.UniqueID)
+
+	g.runInterims(ctx, session, nasPort, start.Add(lifetime))
+
+	session.Duration = int(time.Since(session.StartTime).Seconds())
+	if err := SendAccountingStop(g.Config, session, nasPort, g.Rng); err != nil {
+		log.Printf("loadgen: Acct-Stop failed for %s: %v", user.Username, err)
+	}
+	Metrics.SessionLatency.Observe(time.Since(start).Seconds())
+}
+
+// runInterims sends interim updates, paced by interimLimiter, until
+// deadline passes or ctx is canceled.
+func (g *LoadGenerator) runInterims(ctx context.Context, session *AccountingSession, nasPort int, deadline time.Time) {
+	for time.Now().Before(deadline) {
+		wait := time.Until(deadline)
+		if wait > time.Second {
+			wait = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := g.interimLimiter.Wait(ctx); err != nil {
+			return
+		}
+		elapsed := int(time.Since(session.StartTime).Seconds())
+		if err := SendAccountingInterimUpdate(g.Config, session, nasPort, g.Rng, elapsed); err != nil {
+			log.Printf("loadgen: Interim-Update failed for %s: %v", session.Username, err)
+		}
+	}
+}
+
+func (g *LoadGenerator) startSession(username string, nasPort int, lifetime time.Duration) (*AccountingSession, error) {
+	now := time.Now()
+	session := &AccountingSession{
+		Username:        username,
+		SessionID:       fmt.Sprintf("loadgen-session-%d-%d", now.UnixNano(), nasPort),
+		UniqueID:        gofakeit.UUID(),
+		FramedIPAddress: GenerateRandomIP(g.Rng),
+		StartTime:       now,
+		LastUpdate:      now,
+		Duration:        int(lifetime.Seconds()),
+		Profile:         pickTrafficProfile(g.Rng),
+	}
+	return session, SendAccountingStart(g.Config, session, nasPort)
+}
+
+// sessionLifetime samples a session lifetime uniformly between
+// MinSessionLifetime and MaxSessionLifetime.
+func (g *LoadGenerator) sessionLifetime() time.Duration {
+	min := g.LoadCfg.MinSessionLifetime
+	span := g.LoadCfg.MaxSessionLifetime - min
+	if span <= 0 {
+		return min
+	}
+	return min + time.Duration(g.Rng.Int63()%int64(span))
+}
+
+func (g *LoadGenerator) record(result AuthResult) {
+	for _, sink := range g.Sinks {
+		sink.Record(result)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are the Prometheus collectors every RADIUS exchange in this
+// package reports to, regardless of which binary drives them.
+var Metrics = struct {
+	PacketsSent     *prometheus.CounterVec
+	PacketsReceived *prometheus.CounterVec
+	Retransmits     prometheus.Counter
+	Timeouts        prometheus.Counter
+	ActiveSessions  prometheus.Gauge
+	InFlight        prometheus.Gauge
+	PacketRTT       *prometheus.HistogramVec
+	SessionLatency  prometheus.Histogram
+	AuthTotal       *prometheus.CounterVec
+	AcctTotal       *prometheus.CounterVec
+}{
+	PacketsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "radius_packets_sent_total",
+		Help: "RADIUS packets sent, labeled by packet code.",
+	}, []string{"code"}),
+	PacketsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "radius_packets_received_total",
+		Help: "RADIUS packets received, labeled by packet code.",
+	}, []string{"code"}),
+	Retransmits: prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "radius_retransmits_total",
+		Help: "Request attempts resent after a failed one (timeout, I/O error, or bad or mismatched reply).",
+	}),
+	Timeouts: prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "radius_timeouts_total",
+		Help: "Requests that exhausted all retries without a valid reply.",
+	}),
+	ActiveSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "radius_active_sessions",
+		Help: "Accounting sessions currently between Start and Stop.",
+	}),
+	InFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "radius_inflight_requests",
+		Help: "Requests sent but not yet answered or timed out.",
+	}),
+	PacketRTT: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "radius_packet_rtt_seconds",
+		Help:    "Round-trip time per answered packet, labeled by request code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"code"}),
+	SessionLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "radius_session_duration_seconds",
+		Help:    "Wall-clock time of a LoadGenerator session, from before its Access-Request (rate-limiter wait included) to its Acct-Stop reply.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+	}),
+	AuthTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "radius_auth_total",
+		Help: "Access-Request attempts, labeled by result (accept/reject/timeout).",
+	}, []string{"result"}),
+	AcctTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "radius_acct_total",
+		Help: "Accounting-Request attempts, labeled by type (start/interim/stop).",
+	}, []string{"type"}),
+}
+
+func init() {
+	prometheus.MustRegister(
+		Metrics.PacketsSent,
+		Metrics.PacketsReceived,
+		Metrics.Retransmits,
+		Metrics.Timeouts,
+		Metrics.ActiveSessions,
+		Metrics.InFlight,
+		Metrics.PacketRTT,
+		Metrics.SessionLatency,
+		Metrics.AuthTotal,
+		Metrics.AcctTotal,
+	)
+}
+
+// ServeMetrics exposes /metrics in Prometheus exposition format on addr. It
+// blocks, so callers that want the test to keep running should invoke it in
+// a goroutine.
+func ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Code is a RADIUS packet code (RFC 2865 §3, RFC 2866 §3).
+type Code byte
+
+const (
+	CodeAccessRequest      Code = 1
+	CodeAccessAccept       Code = 2
+	CodeAccessReject       Code = 3
+	CodeAccountingRequest  Code = 4
+	CodeAccountingResponse Code = 5
+	CodeAccessChallenge    Code = 11
+	CodeStatusServer       Code = 12
+	CodeDisconnectRequest  Code = 40
+	CodeDisconnectACK      Code = 41
+	CodeDisconnectNAK      Code = 42
+	CodeCoARequest         Code = 43
+	CodeCoAACK             Code = 44
+	CodeCoANAK             Code = 45
+)
+
+// Standard attribute types used by this package (RFC 2865/2866/2869).
+const (
+	AttrUserName             byte = 1
+	AttrUserPassword         byte = 2
+	AttrCHAPPassword         byte = 3
+	AttrNASIPAddress         byte = 4
+	AttrNASPort              byte = 5
+	AttrServiceType          byte = 6
+	AttrFramedProtocol       byte = 7
+	AttrFramedIPAddress      byte = 8
+	AttrCalledStationID      byte = 30
+	AttrCallingStationID     byte = 31
+	AttrNASIdentifier        byte = 32
+	AttrAcctStatusType       byte = 40
+	AttrAcctInputOctets      byte = 42
+	AttrAcctOutputOctets     byte = 43
+	AttrAcctSessionID        byte = 44
+	AttrAcctAuthentic        byte = 45
+	AttrAcctSessionTime      byte = 46
+	AttrAcctInputPackets     byte = 47
+	AttrAcctOutputPackets    byte = 48
+	AttrAcctTerminateCause   byte = 49
+	AttrCHAPChallenge        byte = 60
+	AttrNASPortType          byte = 61
+	AttrEventTimestamp       byte = 55
+	AttrEAPMessage           byte = 79
+	AttrMessageAuthenticator byte = 80
+	AttrNASPortID            byte = 87
+	AttrSessionTimeout       byte = 27
+	AttrErrorCause           byte = 101
+)
+
+// Error-Cause values (RFC 5176 §3.6) decoded out of a CoA-NAK/Disconnect-NAK.
+const (
+	ErrorCauseResidualSessionContext     uint32 = 201
+	ErrorCauseInvalidEAPPacket           uint32 = 202
+	ErrorCauseUnsupportedAttribute       uint32 = 401
+	ErrorCauseMissingAttribute           uint32 = 402
+	ErrorCauseNASIdentificationMismatch  uint32 = 403
+	ErrorCauseInvalidRequest             uint32 = 404
+	ErrorCauseUnsupportedService         uint32 = 405
+	ErrorCauseUnsupportedExtension       uint32 = 406
+	ErrorCauseAdministrativelyProhibited uint32 = 501
+	ErrorCauseRequestNotRoutable         uint32 = 502
+	ErrorCauseSessionContextNotFound     uint32 = 503
+	ErrorCauseSessionContextNotRemovable uint32 = 504
+	ErrorCauseOtherProxyProcessingError  uint32 = 505
+	ErrorCauseResourcesUnavailable       uint32 = 506
+	ErrorCauseRequestInitiated           uint32 = 507
+	ErrorCauseMultipleSessionsMatch      uint32 = 508
+)
+
+// Acct-Status-Type values (RFC 2866 §5.1).
+const (
+	AcctStatusTypeStart         uint32 = 1
+	AcctStatusTypeStop          uint32 = 2
+	AcctStatusTypeInterimUpdate uint32 = 3
+)
+
+const maxPacketSize = 4096
+
+// Attribute is a single RADIUS attribute-value pair.
+type Attribute struct {
+	Type  byte
+	Value []byte
+}
+
+// Packet is an in-memory representation of a RADIUS packet, encoded and
+// decoded without shelling out to any external tool.
+type Packet struct {
+	Code          Code
+	Identifier    byte
+	Authenticator [16]byte
+	Attributes    []Attribute
+	Secret        []byte
+}
+
+// NewPacket creates a packet with a fresh random Authenticator.
+func NewPacket(code Code, identifier byte, secret []byte) *Packet {
+	p := &Packet{Code: code, Identifier: identifier, Secret: secret}
+	rand.Read(p.Authenticator[:])
+	return p
+}
+
+// Add appends a raw attribute.
+func (p *Packet) Add(attrType byte, value []byte) {
+	p.Attributes = append(p.Attributes, Attribute{Type: attrType, Value: value})
+}
+
+// AttrString adds a string-valued attribute.
+func (p *Packet) AttrString(attrType byte, value string) {
+	p.Add(attrType, []byte(value))
+}
+
+// AttrUint32 adds a 4-octet integer attribute.
+func (p *Packet) AttrUint32(attrType byte, value uint32) {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, value)
+	p.Add(attrType, buf)
+}
+
+// AttrIP adds an IPv4 address attribute.
+func (p *Packet) AttrIP(attrType byte, ip net.IP) {
+	v4 := ip.To4()
+	if v4 == nil {
+		v4 = net.IPv4zero.To4()
+	}
+	p.Add(attrType, []byte(v4))
+}
+
+// Get returns the raw value of the first attribute of the given type.
+func (p *Packet) Get(attrType byte) ([]byte, bool) {
+	for _, a := range p.Attributes {
+		if a.Type == attrType {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// GetAll returns every attribute value of the given type, in packet order
+// (used for fragmented EAP-Message attributes).
+func (p *Packet) GetAll(attrType byte) [][]byte {
+	var out [][]byte
+	for _, a := range p.Attributes {
+		if a.Type == attrType {
+			out = append(out, a.Value)
+		}
+	}
+	return out
+}
+
+// GetString returns the first attribute of the given type as a string.
+func (p *Packet) GetString(attrType byte) (string, bool) {
+	v, ok := p.Get(attrType)
+	if !ok {
+		return "", false
+	}
+	return string(v), true
+}
+
+// DecodeFour decodes the first attribute of the given type as a big-endian uint32.
+func (p *Packet) DecodeFour(attrType byte) (uint32, bool) {
+	v, ok := p.Get(attrType)
+	if !ok || len(v) != 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(v), true
+}
+
+// DecodeIP decodes the first attribute of the given type as an IPv4 address.
+func (p *Packet) DecodeIP(attrType byte) (net.IP, bool) {
+	v, ok := p.Get(attrType)
+	if !ok || len(v) != 4 {
+		return nil, false
+	}
+	return net.IP(v), true
+}
+
+// Encode serializes the packet to wire format.
+func (p *Packet) Encode() ([]byte, error) {
+	var body bytes.Buffer
+	for _, a := range p.Attributes {
+		if len(a.Value) > 253 {
+			return nil, fmt.Errorf("attribute %d too long: %d bytes", a.Type, len(a.Value))
+		}
+		body.WriteByte(a.Type)
+		body.WriteByte(byte(len(a.Value) + 2))
+		body.Write(a.Value)
+	}
+
+	length := 20 + body.Len()
+	if length > maxPacketSize {
+		return nil, fmt.Errorf("packet too large: %d bytes", length)
+	}
+
+	buf := make([]byte, 0, length)
+	buf = append(buf, byte(p.Code), p.Identifier, byte(length>>8), byte(length))
+	buf = append(buf, p.Authenticator[:]...)
+	buf = append(buf, body.Bytes()...)
+	return buf, nil
+}
+
+// DecodePacket parses a wire-format RADIUS packet.
+func DecodePacket(data []byte) (*Packet, error) {
+	if len(data) < 20 {
+		return nil, fmt.Errorf("packet too short: %d bytes", len(data))
+	}
+	length := int(data[2])<<8 | int(data[3])
+	if length < 20 || length > len(data) {
+		return nil, fmt.Errorf("invalid length field: %d (have %d bytes)", length, len(data))
+	}
+
+	p := &Packet{
+		Code:       Code(data[0]),
+		Identifier: data[1],
+	}
+	copy(p.Authenticator[:], data[4:20])
+
+	rest := data[20:length]
+	for len(rest) > 0 {
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("truncated attribute header")
+		}
+		attrType := rest[0]
+		attrLen := int(rest[1])
+		if attrLen < 2 || attrLen > len(rest) {
+			return nil, fmt.Errorf("invalid attribute length %d for type %d", attrLen, attrType)
+		}
+		p.Attributes = append(p.Attributes, Attribute{
+			Type:  attrType,
+			Value: append([]byte(nil), rest[2:attrLen]...),
+		})
+		rest = rest[attrLen:]
+	}
+	return p, nil
+}
+
+// hidePAPPassword implements the RFC 2865 §5.2 User-Password obfuscation:
+// the password is padded to a multiple of 16 bytes, then each 16-byte block
+// is XORed with MD5(secret || previous-ciphertext-block), chained starting
+// from the Request Authenticator.
+func hidePAPPassword(password string, secret []byte, authenticator [16]byte) []byte {
+	pw := []byte(password)
+	padLen := (len(pw) + 15) / 16 * 16
+	if padLen == 0 {
+		padLen = 16
+	}
+	padded := make([]byte, padLen)
+	copy(padded, pw)
+
+	out := make([]byte, padLen)
+	prev := authenticator[:]
+	for i := 0; i < padLen; i += 16 {
+		h := md5.New()
+		h.Write(secret)
+		h.Write(prev)
+		digest := h.Sum(nil)
+
+		block := padded[i : i+16]
+		cipher := make([]byte, 16)
+		for j := 0; j < 16; j++ {
+			cipher[j] = block[j] ^ digest[j]
+		}
+		copy(out[i:i+16], cipher)
+		prev = cipher
+	}
+	return out
+}
+
+// accountingRequestAuthenticator computes the RFC 2866 §3 Request
+// Authenticator: MD5(Code + Identifier + Length + 16-zero-octets + Attributes + Secret).
+func accountingRequestAuthenticator(code Code, identifier byte, attrs []byte, secret []byte) [16]byte {
+	length := 20 + len(attrs)
+	h := md5.New()
+	h.Write([]byte{byte(code), identifier, byte(length >> 8), byte(length)})
+	h.Write(make([]byte, 16))
+	h.Write(attrs)
+	h.Write(secret)
+	var out [16]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// responseAuthenticator computes the expected Response Authenticator for a
+// reply packet, given the request it answers: MD5(Code+ID+Length+RequestAuthenticator+Attrs+Secret).
+func responseAuthenticator(respCode Code, respID byte, respAttrs []byte, requestAuthenticator [16]byte, secret []byte) [16]byte {
+	length := 20 + len(respAttrs)
+	h := md5.New()
+	h.Write([]byte{byte(respCode), respID, byte(length >> 8), byte(length)})
+	h.Write(requestAuthenticator[:])
+	h.Write(respAttrs)
+	h.Write(secret)
+	var out [16]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// signMessageAuthenticator computes and fills in the RFC 2869 §5.14
+// Message-Authenticator attribute: HMAC-MD5 over the whole packet (with the
+// attribute's own value zeroed for the computation), keyed by the shared
+// secret. p must already carry a zero-valued 16-byte Message-Authenticator
+// attribute, added by the caller before signing, so its position and length
+// are fixed in the encoded wire bytes.
+func signMessageAuthenticator(p *Packet) error {
+	wire, err := p.Encode()
+	if err != nil {
+		return fmt.Errorf("encode for message-authenticator: %w", err)
+	}
+
+	h := hmac.New(md5.New, p.Secret)
+	h.Write(wire)
+	sum := h.Sum(nil)
+
+	for i := range p.Attributes {
+		if p.Attributes[i].Type == AttrMessageAuthenticator {
+			copy(p.Attributes[i].Value, sum)
+			return nil
+		}
+	}
+	return fmt.Errorf("packet has no Message-Authenticator attribute to fill in")
+}
+
+func encodeAttrs(attrs []Attribute) []byte {
+	var buf bytes.Buffer
+	for _, a := range attrs {
+		buf.WriteByte(a.Type)
+		buf.WriteByte(byte(len(a.Value) + 2))
+		buf.Write(a.Value)
+	}
+	return buf.Bytes()
+}
+
+// Transport sends a RADIUS packet to addr and waits for the matching reply,
+// making up to Retries attempts in all (at least one), with exponential
+// backoff between them, after a timeout, I/O error or bad reply.
+type Transport struct {
+	Timeout time.Duration
+	Retries int
+}
+
+// udpConns pools idle, already-dialed UDP sockets per destination address so
+// a high-concurrency load generator doesn't pay a dial() syscall on every
+// single Access-Request/Accounting-Request. A dial failure or decode error
+// drops the conn instead of returning it to the pool, since a connectionless
+// UDP socket that's seen a malformed or stray datagram is cheaper to
+// discard than to risk a stale reply surfacing on its next user.
+var (
+	udpConnsMu sync.Mutex
+	udpConns   = map[string][]net.Conn{}
+)
+
+func getUDPConn(addr string) (net.Conn, error) {
+	udpConnsMu.Lock()
+	pool := udpConns[addr]
+	if n := len(pool); n > 0 {
+		conn := pool[n-1]
+		udpConns[addr] = pool[:n-1]
+		udpConnsMu.Unlock()
+		return conn, nil
+	}
+	udpConnsMu.Unlock()
+	return net.Dial("udp", addr)
+}
+
+// udpConnPoolMax caps idle connections kept per address; a load generator
+// ramping concurrency up and back down shouldn't accumulate one socket per
+// worker it ever spawned.
+const udpConnPoolMax = 256
+
+func putUDPConn(addr string, conn net.Conn) {
+	udpConnsMu.Lock()
+	defer udpConnsMu.Unlock()
+	if len(udpConns[addr]) >= udpConnPoolMax {
+		conn.Close()
+		return
+	}
+	udpConns[addr] = append(udpConns[addr], conn)
+}
+
+// retransmitBackoff is the exponential-backoff delay before retry attempt n
+// (n=0 is the first send, so backoff only applies from n=1 on), capped at 1s
+// so a high RADIUS_RETRIES count doesn't stall a single request for minutes.
+func retransmitBackoff(attempt int) time.Duration {
+	const base = 50 * time.Millisecond
+	const maxBackoff = time.Second
+	d := base << uint(attempt-1)
+	if d > maxBackoff || d <= 0 {
+		return maxBackoff
+	}
+	return d
+}
+
+// Exchange sends req to addr over UDP and returns the decoded response along
+// with the measured round-trip time.
+func (t *Transport) Exchange(addr string, req *Packet) (*Packet, time.Duration, error) {
+	wire, err := req.Encode()
+	if err != nil {
+		return nil, 0, fmt.Errorf("encode request: %w", err)
+	}
+
+	conn, err := getUDPConn(addr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	keepConn := false
+	defer func() {
+		if keepConn {
+			putUDPConn(addr, conn)
+		} else {
+			conn.Close()
+		}
+	}()
+
+	retries := t.Retries
+	if retries < 1 {
+		retries = 1
+	}
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	buf := make([]byte, maxPacketSize)
+	start := time.Now()
+
+	codeLabel := fmt.Sprintf("%d", req.Code)
+	Metrics.InFlight.Inc()
+	defer Metrics.InFlight.Dec()
+
+	var lastErr error
+	for attempt := 0; attempt < retries; attempt++ {
+		if attempt > 0 {
+			Metrics.Retransmits.Inc()
+			time.Sleep(retransmitBackoff(attempt))
+		}
+		if _, err := conn.Write(wire); err != nil {
+			lastErr = fmt.Errorf("write: %w", err)
+			continue
+		}
+		Metrics.PacketsSent.WithLabelValues(codeLabel).Inc()
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			lastErr = fmt.Errorf("read: %w", err)
+			continue
+		}
+		rtt := time.Since(start)
+
+		resp, err := DecodePacket(buf[:n])
+		if err != nil {
+			lastErr = fmt.Errorf("decode response: %w", err)
+			continue
+		}
+		if resp.Identifier != req.Identifier {
+			lastErr = fmt.Errorf("identifier mismatch: sent %d, got %d", req.Identifier, resp.Identifier)
+			continue
+		}
+
+		Metrics.PacketsReceived.WithLabelValues(fmt.Sprintf("%d", resp.Code)).Inc()
+		Metrics.PacketRTT.WithLabelValues(codeLabel).Observe(rtt.Seconds())
+		keepConn = true
+		return resp, rtt, nil
+	}
+	Metrics.Timeouts.Inc()
+	return nil, time.Since(start), lastErr
+}
+
+var identifierCounter uint32
+
+// nextIdentifier hands out sequential RADIUS packet identifiers. Built on
+// atomic.AddUint32 rather than a plain byte++ so concurrent load-generator
+// workers don't race on the same counter and silently reuse identifiers,
+// which would make Transport.Exchange's identifier-mismatch check misfire.
+func nextIdentifier() byte {
+	return byte(atomic.AddUint32(&identifierCounter, 1))
+}
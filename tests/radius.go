@@ -1,23 +1,26 @@
 package main
 
 import (
-	"bytes"
+	"encoding/binary"
 	"fmt"
 	"log"
-	"math/rand"
-	"os"
-	"os/exec"
-	"strings"
+	"net"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 )
 
+// AuthResult holds the outcome of a native Access-Request exchange.
 type AuthResult struct {
-	Username  string
-	Success   bool
-	Message   string
-	Timestamp time.Time
+	Username    string
+	Success     bool
+	Code        Code
+	Attributes  []Attribute
+	RTT         time.Duration
+	MessageAuth bool       // true if the reply's Message-Authenticator validated
+	Method      AuthMethod // authentication scheme used for this attempt
+	RoundTrips  int        // number of Access-Request/Access-Challenge round trips
+	Timestamp   time.Time
 }
 
 type AccountingSession struct {
@@ -26,209 +29,254 @@ type AccountingSession struct {
 	UniqueID        string
 	FramedIPAddress string
 	StartTime       time.Time
+	LastUpdate      time.Time
 	Duration        int
 	BytesIn         int64
 	BytesOut        int64
+	PacketsIn       int64
+	PacketsOut      int64
+	Profile         TrafficProfile
+	Model           TrafficModel // overrides Profile's Gaussian sampling when set
 }
 
-// TestAuthentication tests RADIUS authentication for a user
-func TestAuthentication(config *Config, username, password string) (*AuthResult, error) {
-	cmd := exec.Command(
-		"radtest",
-		username,
-		password,
-		config.RadiusServer,
-		config.RadiusAuthPort,
-		config.RadiusSecret,
-	)
-
-	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
+func authAddr(config *Config) string {
+	return fmt.Sprintf("%s:%s", config.RadiusServer, config.RadiusAuthPort)
+}
 
-	result := &AuthResult{
-		Username:  username,
-		Success:   strings.Contains(outputStr, "Access-Accept"),
-		Message:   outputStr,
-		Timestamp: time.Now(),
-	}
+func acctAddr(config *Config) string {
+	return fmt.Sprintf("%s:%s", config.RadiusServer, config.RadiusAcctPort)
+}
 
-	if err != nil && !result.Success {
-		return result, fmt.Errorf("authentication failed: %w", err)
+// transport returns the Exchanger matching config.Transport: a fresh UDP
+// Transport per call (the default), or a cached StreamTransport that
+// reuses a single long-lived TCP/RadSec connection per address.
+func transport(config *Config) Exchanger {
+	switch config.Transport {
+	case TransportTCP, TransportTLS:
+		return &configTransport{config: config}
+	default:
+		return &Transport{Timeout: config.RadiusTimeout, Retries: config.RadiusRetries}
 	}
+}
 
-	return result, nil
+// TestAuthentication sends a native PAP Access-Request and reports the result.
+func TestAuthentication(config *Config, username, password string) (*AuthResult, error) {
+	return TestAuthenticationWithAttrs(config, username, password, nil)
 }
 
-// SendAccountingStart sends an Accounting-Start packet
-func SendAccountingStart(config *Config, session *AccountingSession, nasPort int) error {
-	tempFile := fmt.Sprintf("/tmp/acct-start-%s.txt", session.SessionID)
-	defer os.Remove(tempFile)
-
-	content := fmt.Sprintf(`Acct-Status-Type = Start
-User-Name = "%s"
-NAS-IP-Address = %s
-NAS-Port = %d
-NAS-Port-Type = Ethernet
-NAS-Port-Id = "%s"
-Acct-Session-Id = "%s"
-Acct-Unique-Session-Id = "%s"
-Framed-IP-Address = %s
-Acct-Authentic = RADIUS
-Service-Type = Framed-User
-Framed-Protocol = PPP
-Event-Timestamp = %d
-`,
-		session.Username,
-		config.NASIPAddress,
-		nasPort,
-		config.NASPortID,
-		session.SessionID,
-		session.UniqueID,
-		session.FramedIPAddress,
-		session.StartTime.Unix(),
-	)
+// TestAuthenticationWithAttrs is TestAuthentication with extra attributes
+// (e.g. a scenario step's Called-Station-Id or NAS-Port-Type override)
+// appended to the Access-Request before it's signed and sent.
+func TestAuthenticationWithAttrs(config *Config, username, password string, extra []Attribute) (*AuthResult, error) {
+	req := NewPacket(CodeAccessRequest, nextIdentifier(), []byte(config.RadiusSecret))
+	req.AttrString(AttrUserName, username)
+	req.Add(AttrUserPassword, hidePAPPassword(password, req.Secret, req.Authenticator))
+	if ip := net.ParseIP(config.NASIPAddress); ip != nil {
+		req.AttrIP(AttrNASIPAddress, ip)
+	}
+	req.Attributes = append(req.Attributes, extra...)
+	req.Add(AttrMessageAuthenticator, make([]byte, 16))
+	if err := signMessageAuthenticator(req); err != nil {
+		return nil, fmt.Errorf("sign message-authenticator: %w", err)
+	}
 
-	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+	resp, rtt, err := transport(config).Exchange(authAddr(config), req)
+	if err != nil {
+		recordAuthResult("timeout", rtt)
+		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
-	cmd := exec.Command(
-		"radclient",
-		"-f", tempFile,
-		fmt.Sprintf("%s:%s", config.RadiusServer, config.RadiusAcctPort),
-		"acct",
-		config.RadiusSecret,
-	)
+	authLabel := "reject"
+	if resp.Code == CodeAccessAccept {
+		authLabel = "accept"
+	}
+	recordAuthResult(authLabel, rtt)
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("radclient failed: %w\nOutput: %s", err, string(output))
+	result := &AuthResult{
+		Username:   username,
+		Success:    resp.Code == CodeAccessAccept,
+		Code:       resp.Code,
+		Attributes: resp.Attributes,
+		RTT:        rtt,
+		Timestamp:  time.Now(),
 	}
 
-	return nil
+	return result, nil
 }
 
-// SendAccountingInterimUpdate sends an Accounting Interim-Update packet
-func SendAccountingInterimUpdate(config *Config, session *AccountingSession, nasPort int, currentDuration int) error {
-	tempFile := fmt.Sprintf("/tmp/acct-update-%s.txt", session.SessionID)
-	defer os.Remove(tempFile)
-
-	content := fmt.Sprintf(`Acct-Status-Type = Interim-Update
-User-Name = "%s"
-NAS-IP-Address = %s
-NAS-Port = %d
-NAS-Port-Id = "%s"
-Acct-Session-Id = "%s"
-Acct-Unique-Session-Id = "%s"
-Framed-IP-Address = %s
-Acct-Session-Time = %d
-Acct-Input-Octets = %d
-Acct-Output-Octets = %d
-Acct-Input-Packets = %d
-Acct-Output-Packets = %d
-Event-Timestamp = %d
-`,
-		session.Username,
-		config.NASIPAddress,
-		nasPort,
-		config.NASPortID,
-		session.SessionID,
-		session.UniqueID,
-		session.FramedIPAddress,
-		currentDuration,
-		session.BytesIn/2,
-		session.BytesOut/2,
-		rand.Intn(10000),
-		rand.Intn(8000),
-		time.Now().Unix(),
-	)
+func accountingAttrs(config *Config, session *AccountingSession, nasPort int) []Attribute {
+	p := &Packet{}
+	p.AttrString(AttrUserName, session.Username)
+	if ip := net.ParseIP(config.NASIPAddress); ip != nil {
+		p.AttrIP(AttrNASIPAddress, ip)
+	}
+	p.AttrUint32(AttrNASPort, uint32(nasPort))
+	p.AttrString(AttrNASPortID, config.NASPortID)
+	p.AttrString(AttrAcctSessionID, session.SessionID)
+	if ip := net.ParseIP(session.FramedIPAddress); ip != nil {
+		p.AttrIP(AttrFramedIPAddress, ip)
+	}
+	return p.Attributes
+}
 
-	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+// acctStatusTypeLabel reads attrs' Acct-Status-Type back out as the
+// start/interim/stop label recordAcctResult reports to metricsSinks.
+func acctStatusTypeLabel(attrs []Attribute) string {
+	for _, a := range attrs {
+		if a.Type != AttrAcctStatusType || len(a.Value) != 4 {
+			continue
+		}
+		switch binary.BigEndian.Uint32(a.Value) {
+		case AcctStatusTypeStart:
+			return "start"
+		case AcctStatusTypeInterimUpdate:
+			return "interim"
+		case AcctStatusTypeStop:
+			return "stop"
+		}
 	}
+	return "unknown"
+}
 
-	cmd := exec.Command(
-		"radclient",
-		"-f", tempFile,
-		fmt.Sprintf("%s:%s", config.RadiusServer, config.RadiusAcctPort),
-		"acct",
-		config.RadiusSecret,
-	)
+// sendAcct builds, signs, sends, and validates an Accounting-Request.
+func sendAcct(config *Config, attrs []Attribute) error {
+	identifier := nextIdentifier()
+	secret := []byte(config.RadiusSecret)
+	wireAttrs := encodeAttrs(attrs)
+	authenticator := accountingRequestAuthenticator(CodeAccountingRequest, identifier, wireAttrs, secret)
+
+	req := &Packet{
+		Code:          CodeAccountingRequest,
+		Identifier:    identifier,
+		Authenticator: authenticator,
+		Attributes:    attrs,
+		Secret:        secret,
+	}
 
-	output, err := cmd.CombinedOutput()
+	resp, rtt, err := transport(config).Exchange(acctAddr(config), req)
+	recordAcctResult(acctStatusTypeLabel(attrs), rtt)
 	if err != nil {
-		return fmt.Errorf("radclient failed: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("accounting exchange failed: %w", err)
+	}
+	if resp.Code != CodeAccountingResponse {
+		return fmt.Errorf("unexpected response code %d", resp.Code)
+	}
+
+	respAttrs := encodeAttrs(resp.Attributes)
+	want := responseAuthenticator(resp.Code, resp.Identifier, respAttrs, authenticator, secret)
+	if want != resp.Authenticator {
+		return fmt.Errorf("response authenticator validation failed")
 	}
 
 	return nil
 }
 
-// SendAccountingStop sends an Accounting-Stop packet
-func SendAccountingStop(config *Config, session *AccountingSession, nasPort int) error {
-	tempFile := fmt.Sprintf("/tmp/acct-stop-%s.txt", session.SessionID)
-	defer os.Remove(tempFile)
-
-	content := fmt.Sprintf(`Acct-Status-Type = Stop
-User-Name = "%s"
-NAS-IP-Address = %s
-NAS-Port = %d
-NAS-Port-Id = "%s"
-Acct-Session-Id = "%s"
-Acct-Unique-Session-Id = "%s"
-Framed-IP-Address = %s
-Acct-Session-Time = %d
-Acct-Input-Octets = %d
-Acct-Output-Octets = %d
-Acct-Input-Packets = %d
-Acct-Output-Packets = %d
-Acct-Terminate-Cause = User-Request
-Event-Timestamp = %d
-`,
-		session.Username,
-		config.NASIPAddress,
-		nasPort,
-		config.NASPortID,
-		session.SessionID,
-		session.UniqueID,
-		session.FramedIPAddress,
-		session.Duration,
-		session.BytesIn,
-		session.BytesOut,
-		rand.Intn(20000),
-		rand.Intn(15000),
-		time.Now().Unix(),
-	)
+// SendAccountingStart sends an Accounting-Start packet.
+func SendAccountingStart(config *Config, session *AccountingSession, nasPort int) error {
+	return SendAccountingStartWithAttrs(config, session, nasPort, nil)
+}
 
-	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
-	}
+// SendAccountingStartWithAttrs is SendAccountingStart with extra attributes
+// (e.g. a scenario step's Called-Station-Id or NAS-Port-Type override)
+// appended after the defaults, so a step-specific value takes precedence
+// over "Ethernet" when the server reads the last occurrence of a type.
+func SendAccountingStartWithAttrs(config *Config, session *AccountingSession, nasPort int, extra []Attribute) error {
+	attrs := accountingAttrs(config, session, nasPort)
+	p := &Packet{Attributes: attrs}
+	p.AttrUint32(AttrAcctStatusType, AcctStatusTypeStart)
+	p.AttrString(AttrNASPortType, "Ethernet")
+	p.AttrUint32(AttrServiceType, 2) // Framed-User
+	p.AttrUint32(AttrFramedProtocol, 1)
+	p.AttrUint32(AttrAcctAuthentic, 1) // RADIUS
+	p.AttrUint32(AttrEventTimestamp, uint32(session.StartTime.Unix()))
+	p.Attributes = append(p.Attributes, extra...)
+
+	return sendAcct(config, p.Attributes)
+}
 
-	cmd := exec.Command(
-		"radclient",
-		"-f", tempFile,
-		fmt.Sprintf("%s:%s", config.RadiusServer, config.RadiusAcctPort),
-		"acct",
-		config.RadiusSecret,
-	)
+// SendAccountingInterimUpdate samples the profile's traffic model over the
+// interval since the session's last report, advances BytesIn/BytesOut/
+// PacketsIn/PacketsOut monotonically, and sends the resulting Interim-Update.
+func SendAccountingInterimUpdate(config *Config, session *AccountingSession, nasPort int, rng RNG, currentDuration int) error {
+	advanceSessionTraffic(session, rng)
+
+	attrs := accountingAttrs(config, session, nasPort)
+	p := &Packet{Attributes: attrs}
+	p.AttrUint32(AttrAcctStatusType, AcctStatusTypeInterimUpdate)
+	p.AttrUint32(AttrAcctSessionTime, uint32(currentDuration))
+	p.AttrUint32(AttrAcctInputOctets, uint32(session.BytesIn))
+	p.AttrUint32(AttrAcctOutputOctets, uint32(session.BytesOut))
+	p.AttrUint32(AttrAcctInputPackets, uint32(session.PacketsIn))
+	p.AttrUint32(AttrAcctOutputPackets, uint32(session.PacketsOut))
+	p.AttrUint32(AttrEventTimestamp, uint32(time.Now().Unix()))
+
+	return sendAcct(config, p.Attributes)
+}
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("radclient failed: %w\nOutput: %s", err, string(output))
+// SendAccountingStop advances the session's traffic counters one final time
+// up to the moment of termination and sends an Accounting-Stop with
+// Acct-Terminate-Cause = User-Request.
+func SendAccountingStop(config *Config, session *AccountingSession, nasPort int, rng RNG) error {
+	return SendAccountingStopWithCause(config, session, nasPort, rng, "User-Request")
+}
+
+// SendAccountingStopWithCause is SendAccountingStop with an explicit
+// Acct-Terminate-Cause, e.g. "Admin-Reset" when an operator disconnects the
+// session via CoA/Disconnect-Request rather than it ending on its own.
+func SendAccountingStopWithCause(config *Config, session *AccountingSession, nasPort int, rng RNG, cause string) error {
+	advanceSessionTraffic(session, rng)
+
+	attrs := accountingAttrs(config, session, nasPort)
+	p := &Packet{Attributes: attrs}
+	p.AttrUint32(AttrAcctStatusType, AcctStatusTypeStop)
+	p.AttrUint32(AttrAcctSessionTime, uint32(session.Duration))
+	p.AttrUint32(AttrAcctInputOctets, uint32(session.BytesIn))
+	p.AttrUint32(AttrAcctOutputOctets, uint32(session.BytesOut))
+	p.AttrUint32(AttrAcctInputPackets, uint32(session.PacketsIn))
+	p.AttrUint32(AttrAcctOutputPackets, uint32(session.PacketsOut))
+	p.AttrString(AttrAcctTerminateCause, cause)
+	p.AttrUint32(AttrEventTimestamp, uint32(time.Now().Unix()))
+
+	return sendAcct(config, p.Attributes)
+}
+
+// advanceSessionTraffic samples the session's traffic shape over the elapsed
+// time since LastUpdate and adds the resulting deltas to the running
+// byte/packet totals. It uses session.Model when set (the pluggable
+// -traffic-model shapes), falling back to the Gaussian session.Profile
+// otherwise.
+func advanceSessionTraffic(session *AccountingSession, rng RNG) {
+	now := time.Now()
+	elapsed := now.Sub(session.LastUpdate)
+
+	var bytesIn, bytesOut int64
+	if session.Model != nil {
+		bytesIn, bytesOut = session.Model.Next(elapsed)
+	} else {
+		bytesIn, bytesOut, _, _ = session.Profile.advance(rng, elapsed.Seconds())
 	}
 
-	return nil
+	meanPacketBytes := session.Profile.MeanPacketBytes
+	if meanPacketBytes <= 0 {
+		meanPacketBytes = 900
+	}
+	session.BytesIn += bytesIn
+	session.BytesOut += bytesOut
+	session.PacketsIn += int64(float64(bytesIn)/meanPacketBytes) + 1
+	session.PacketsOut += int64(float64(bytesOut)/meanPacketBytes) + 1
+	session.LastUpdate = now
 }
 
-// GenerateRandomIP generates a random IP address
-func GenerateRandomIP() string {
+// GenerateRandomIP generates a random IP address using rng.
+func GenerateRandomIP(rng RNG) string {
 	return fmt.Sprintf("10.%d.%d.%d",
-		rand.Intn(256),
-		rand.Intn(256),
-		rand.Intn(256),
+		rng.Intn(256),
+		rng.Intn(256),
+		rng.Intn(256),
 	)
 }
 
-// FormatBytes formats bytes into human-readable format
+// FormatBytes formats bytes into human-readable format.
 func FormatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -242,7 +290,7 @@ func FormatBytes(bytes int64) string {
 	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-// FormatDuration formats duration in seconds to human-readable format
+// FormatDuration formats duration in seconds to human-readable format.
 func FormatDuration(seconds int) string {
 	duration := time.Duration(seconds) * time.Second
 	hours := int(duration.Hours())
@@ -257,21 +305,27 @@ func FormatDuration(seconds int) string {
 	return fmt.Sprintf("%ds", secs)
 }
 
-// CreateAccountingSession creates a complete accounting session
-func CreateAccountingSession(config *Config, username string, nasPort int) (*AccountingSession, error) {
+// CreateAccountingSession creates a complete accounting session, drawing its
+// traffic profile, IP, and duration from rng so a run is reproducible given
+// the same seed.
+func CreateAccountingSession(config *Config, username string, nasPort int, rng RNG) (*AccountingSession, error) {
+	now := time.Now()
 	session := &AccountingSession{
 		Username:        username,
-		SessionID:       fmt.Sprintf("test-session-%d-%d", time.Now().Unix(), nasPort),
+		SessionID:       fmt.Sprintf("test-session-%d-%d", now.Unix(), nasPort),
 		UniqueID:        gofakeit.UUID(),
-		FramedIPAddress: GenerateRandomIP(),
-		StartTime:       time.Now(),
-		Duration:        rand.Intn(3600) + 300,        // 5 minutes to 1 hour
-		BytesIn:         int64(rand.Intn(1000000000)), // Up to 1GB
-		BytesOut:        int64(rand.Intn(500000000)),  // Up to 500MB
+		FramedIPAddress: GenerateRandomIP(rng),
+		StartTime:       now,
+		LastUpdate:      now,
+		Duration:        rng.Intn(3600) + 300, // 5 minutes to 1 hour
+		Profile:         pickTrafficProfile(rng),
 	}
 
 	log.Printf("  → Session ID: %s", session.SessionID)
 	log.Printf("  → IP: %s", session.FramedIPAddress)
+	log.Printf("  → Traffic profile: %s", session.Profile.Name)
+
+	activeSessions.Register(session)
 
 	// Send Start
 	log.Printf("  → Sending Acct-Start...")
@@ -284,7 +338,7 @@ func CreateAccountingSession(config *Config, username string, nasPort int) (*Acc
 
 	// Send Interim-Update
 	log.Printf("  → Sending Interim-Update...")
-	if err := SendAccountingInterimUpdate(config, session, nasPort, session.Duration/2); err != nil {
+	if err := SendAccountingInterimUpdate(config, session, nasPort, rng, session.Duration/2); err != nil {
 		return nil, fmt.Errorf("failed to send interim update: %w", err)
 	}
 
@@ -293,9 +347,10 @@ func CreateAccountingSession(config *Config, username string, nasPort int) (*Acc
 
 	// Send Stop
 	log.Printf("  → Sending Acct-Stop...")
-	if err := SendAccountingStop(config, session, nasPort); err != nil {
+	if err := SendAccountingStop(config, session, nasPort, rng); err != nil {
 		return nil, fmt.Errorf("failed to send accounting stop: %w", err)
 	}
+	activeSessions.Remove(session.UniqueID)
 
 	log.Printf("  ✓ Session completed: Duration=%s, Download=%s, Upload=%s",
 		FormatDuration(session.Duration),
@@ -306,7 +361,7 @@ func CreateAccountingSession(config *Config, username string, nasPort int) (*Acc
 	return session, nil
 }
 
-// PrintAuthResults prints authentication test results
+// PrintAuthResults prints authentication test results.
 func PrintAuthResults(results []AuthResult) {
 	var successCount, failCount int
 	for _, result := range results {
@@ -328,25 +383,3 @@ func PrintAuthResults(results []AuthResult) {
 	}
 	fmt.Println("========================================")
 }
-
-// VerifyRadclientInstalled checks if radclient and radtest are installed
-func VerifyRadclientInstalled() error {
-	// Check radtest
-	cmd := exec.Command("which", "radtest")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("radtest not found. Please install freeradius-utils: brew install freeradius-server")
-	}
-
-	// Check radclient
-	cmd = exec.Command("which", "radclient")
-	out.Reset()
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("radclient not found. Please install freeradius-utils: brew install freeradius-server")
-	}
-
-	log.Println("✓ radtest and radclient are installed")
-	return nil
-}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PacketKind distinguishes the three accounting packet types the reporter
+// tracks latency for.
+type PacketKind int
+
+const (
+	PacketStart PacketKind = iota
+	PacketInterim
+	PacketStop
+)
+
+func (k PacketKind) String() string {
+	switch k {
+	case PacketStart:
+		return "Start"
+	case PacketInterim:
+		return "Interim"
+	case PacketStop:
+		return "Stop"
+	default:
+		return "Unknown"
+	}
+}
+
+// Outcome is the result of a single accounting exchange, classified from the
+// error sendAcct returned.
+type Outcome int
+
+const (
+	OutcomeSuccess Outcome = iota
+	OutcomeTimeout
+	OutcomeReject
+)
+
+// classifyOutcome maps a send error to an Outcome. err is nil on success; a
+// timed-out read/dial (anything satisfying net.Error with Timeout() true)
+// is a Timeout, and anything else (bad response code, authenticator
+// mismatch, ...) is a Reject.
+func classifyOutcome(err error) Outcome {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return OutcomeTimeout
+	}
+	return OutcomeReject
+}
+
+// result is one completed accounting exchange, as handed to the Reporter.
+type result struct {
+	Kind    PacketKind
+	Outcome Outcome
+	Latency time.Duration
+}
+
+// Reporter collects a latency/outcome sample for every accounting packet
+// sent by the simulator on a buffered channel, aggregates them on a single
+// background goroutine, and on shutdown prints a hey/boom-style summary:
+// totals, RPS, fastest/slowest/average, a text histogram, and p50/p90/p95/p99
+// percentiles, each broken down by packet type. Running totals are also kept
+// in atomic int64s so a periodic JSON snapshot can be read without locking
+// the hot path every send goes through.
+type Reporter struct {
+	results chan result
+	wg      sync.WaitGroup
+	start   time.Time
+
+	total, success, timeouts, rejects int64
+
+	mu      sync.Mutex // guards samples; held only by run() and the read-side summary/snapshot methods
+	samples map[PacketKind][]time.Duration
+}
+
+// NewReporter creates a Reporter and starts its aggregation goroutine.
+func NewReporter() *Reporter {
+	r := &Reporter{
+		results: make(chan result, 4096),
+		start:   time.Now(),
+		samples: make(map[PacketKind][]time.Duration),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+// Record queues one completed exchange. It never blocks: if the aggregation
+// goroutine has fallen behind, the sample is dropped rather than stalling
+// the caller's accounting traffic.
+func (r *Reporter) Record(kind PacketKind, outcome Outcome, latency time.Duration) {
+	select {
+	case r.results <- result{Kind: kind, Outcome: outcome, Latency: latency}:
+	default:
+	}
+}
+
+func (r *Reporter) run() {
+	defer r.wg.Done()
+	for res := range r.results {
+		atomic.AddInt64(&r.total, 1)
+		switch res.Outcome {
+		case OutcomeSuccess:
+			atomic.AddInt64(&r.success, 1)
+		case OutcomeTimeout:
+			atomic.AddInt64(&r.timeouts, 1)
+		case OutcomeReject:
+			atomic.AddInt64(&r.rejects, 1)
+		}
+
+		r.mu.Lock()
+		r.samples[res.Kind] = append(r.samples[res.Kind], res.Latency)
+		r.mu.Unlock()
+	}
+}
+
+// Close drains and stops the aggregation goroutine. Call it after every
+// sender has stopped calling Record, and before PrintSummary.
+func (r *Reporter) Close() {
+	close(r.results)
+	r.wg.Wait()
+}
+
+// snapshot is the JSON shape written to -report-json.
+type snapshot struct {
+	Timestamp time.Time `json:"timestamp"`
+	Elapsed   float64   `json:"elapsed_seconds"`
+	Total     int64     `json:"total"`
+	Success   int64     `json:"success"`
+	Timeouts  int64     `json:"timeouts"`
+	Rejects   int64     `json:"rejects"`
+	RPS       float64   `json:"rps"`
+}
+
+// Snapshot reads the running totals via atomics, with no locking, so it's
+// safe to call from a periodic goroutine alongside the hot send path.
+func (r *Reporter) Snapshot() snapshot {
+	total := atomic.LoadInt64(&r.total)
+	elapsed := time.Since(r.start).Seconds()
+	var rps float64
+	if elapsed > 0 {
+		rps = float64(total) / elapsed
+	}
+	return snapshot{
+		Timestamp: time.Now(),
+		Elapsed:   elapsed,
+		Total:     total,
+		Success:   atomic.LoadInt64(&r.success),
+		Timeouts:  atomic.LoadInt64(&r.timeouts),
+		Rejects:   atomic.LoadInt64(&r.rejects),
+		RPS:       rps,
+	}
+}
+
+// RunPeriodicJSON writes a Snapshot to path every interval until ctx is
+// canceled, so external dashboards can tail the file for a running view of
+// the simulator's progress.
+func (r *Reporter) RunPeriodicJSON(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.writeJSON(path); err != nil {
+				fmt.Printf("report-json: %v\n", err)
+			}
+		}
+	}
+}
+
+func (r *Reporter) writeJSON(path string) error {
+	data, err := json.MarshalIndent(r.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// PrintSummary prints a hey/boom-style summary of everything recorded so
+// far: totals, RPS, and a latency histogram with percentiles per packet
+// type. Call it after Close, once every sender has stopped recording.
+func (r *Reporter) PrintSummary() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := time.Since(r.start)
+	total := atomic.LoadInt64(&r.total)
+
+	fmt.Println("\n========================================")
+	fmt.Println("Load Test Summary")
+	fmt.Println("========================================")
+	fmt.Printf("Total requests:     %d\n", total)
+	fmt.Printf("Successful:         %d\n", atomic.LoadInt64(&r.success))
+	fmt.Printf("Timeouts:           %d\n", atomic.LoadInt64(&r.timeouts))
+	fmt.Printf("Rejected:           %d\n", atomic.LoadInt64(&r.rejects))
+	fmt.Printf("Duration:           %s\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Printf("Requests/sec:       %.2f\n", float64(total)/elapsed.Seconds())
+	}
+
+	for _, kind := range []PacketKind{PacketStart, PacketInterim, PacketStop} {
+		samples := r.samples[kind]
+		if len(samples) == 0 {
+			continue
+		}
+		printLatencyBreakdown(kind.String(), samples)
+	}
+	fmt.Println("========================================")
+}
+
+// printLatencyBreakdown prints the fastest/slowest/average, percentiles, and
+// a text histogram for one packet type's latency samples.
+func printLatencyBreakdown(label string, samples []time.Duration) {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, s := range sorted {
+		sum += s
+	}
+	avg := sum / time.Duration(len(sorted))
+
+	fmt.Printf("\n--- %s (%d samples) ---\n", label, len(sorted))
+	fmt.Printf("  Fastest: %s   Slowest: %s   Average: %s\n",
+		sorted[0].Round(time.Microsecond),
+		sorted[len(sorted)-1].Round(time.Microsecond),
+		avg.Round(time.Microsecond),
+	)
+
+	fmt.Println("  Latency distribution:")
+	for _, p := range []float64{50, 90, 95, 99} {
+		fmt.Printf("    p%.0f: %s\n", p, percentile(sorted, p).Round(time.Microsecond))
+	}
+
+	fmt.Println("  Histogram:")
+	printHistogram(sorted)
+}
+
+// percentile returns the p-th percentile (0-100) of sorted latencies by
+// nearest rank. sorted must already be sorted ascending.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p/100*float64(len(sorted)-1) + 0.5)
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// printHistogram buckets sorted latencies into 10 equal-width buckets
+// spanning [fastest, slowest] and prints a bar per bucket, hey/boom style.
+func printHistogram(sorted []time.Duration) {
+	const buckets = 10
+	fastest, slowest := sorted[0], sorted[len(sorted)-1]
+	width := slowest - fastest
+	if width <= 0 {
+		fmt.Printf("    %s [%d]\t%s\n", fastest.Round(time.Microsecond), len(sorted), bar(len(sorted), len(sorted)))
+		return
+	}
+
+	counts := make([]int, buckets)
+	for _, s := range sorted {
+		idx := int(float64(s-fastest) / float64(width) * buckets)
+		if idx >= buckets {
+			idx = buckets - 1
+		}
+		counts[idx]++
+	}
+
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	for i, c := range counts {
+		lower := fastest + time.Duration(float64(width)*float64(i)/buckets)
+		fmt.Printf("    %s [%d]\t%s\n", lower.Round(time.Microsecond), c, bar(c, max))
+	}
+}
+
+// bar renders count as a block-character bar scaled against max.
+func bar(count, max int) string {
+	if max == 0 {
+		return ""
+	}
+	const width = 40
+	return strings.Repeat("■", count*width/max)
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// ─── Scenario files ─────────────────────────────────────────────────────────
+//
+// -scenario scenarios/foo.yaml replaces the flat -sessions/-concurrent
+// workload with a structured description an operator writes themselves:
+//
+//   warmup: 10s
+//   phases:
+//     - duration: 1m
+//       arrivalRate: 50
+//       rampTo: 500
+//   flows:
+//     - name: auth-only
+//       weight: 40
+//       steps:
+//         - type: auth
+//     - name: full-session
+//       weight: 30
+//       steps:
+//         - type: auth
+//         - type: acct_start
+//         - type: acct_stop
+//     - name: long-session
+//       weight: 20
+//       steps:
+//         - type: auth
+//         - type: acct_start
+//         - type: acct_interim
+//           repeat: 3
+//         - type: acct_stop
+//     - name: bad-password-probe
+//       weight: 10
+//       steps:
+//         - type: auth_reject
+//
+// Phases run in order; a phase's arrival rate ramps linearly from
+// arrivalRate to rampTo over its duration (rampTo: 0 or omitted means a
+// constant rate). Arrivals are scheduled open-model: a new virtual user
+// starts on schedule regardless of whether earlier ones have finished,
+// matching "500 auth/sec for 5 minutes" rather than a fixed concurrency
+// pool waiting on each other.
+
+// ScenarioFile is the top-level shape of a -scenario YAML document.
+type ScenarioFile struct {
+	Warmup string      `yaml:"warmup"`
+	Phases []PhaseSpec `yaml:"phases"`
+	Flows  []FlowSpec  `yaml:"flows"`
+}
+
+// PhaseSpec is one entry under `phases:`.
+type PhaseSpec struct {
+	Duration    string  `yaml:"duration"`
+	ArrivalRate float64 `yaml:"arrivalRate"`
+	RampTo      float64 `yaml:"rampTo"`
+}
+
+// FlowSpec is one named request chain under `flows:`, picked per arrival
+// with probability Weight / sum(all weights).
+type FlowSpec struct {
+	Name   string     `yaml:"name"`
+	Weight float64    `yaml:"weight"`
+	Steps  []StepSpec `yaml:"steps"`
+}
+
+// StepSpec is one request in a flow. Type selects which of the existing
+// TestAuthentication*/SendAccounting* helpers to call; unused fields are
+// ignored by steps that don't need them.
+type StepSpec struct {
+	Type            string `yaml:"type"` // auth | auth_reject | acct_start | acct_interim | acct_stop
+	Repeat          int    `yaml:"repeat"`
+	ThinkMin        string `yaml:"thinkMin"`
+	ThinkMax        string `yaml:"thinkMax"`
+	CalledStationID string `yaml:"calledStationId"`
+	NASPortType     string `yaml:"nasPortType"`
+	TerminateCause  string `yaml:"terminateCause"`
+}
+
+// Scenario is a parsed, ready-to-run ScenarioFile: durations resolved and
+// flow weights turned into a cumulative distribution that pickFlow scans
+// linearly.
+type Scenario struct {
+	Warmup time.Duration
+	Phases []resolvedPhase
+	Flows  []FlowSpec
+
+	cumWeights []float64
+	totalW     float64
+}
+
+type resolvedPhase struct {
+	Duration    time.Duration
+	ArrivalRate float64
+	RampTo      float64
+}
+
+// LoadScenario reads and parses path, resolving durations and flow weights.
+// A flow with no steps or a step with an unknown type fails the whole load,
+// the same "typo stops the run" contract the rest of this tool uses for
+// -scenario-style config.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var file ScenarioFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if len(file.Flows) == 0 {
+		return nil, fmt.Errorf("%s: no flows defined", path)
+	}
+	if len(file.Phases) == 0 {
+		return nil, fmt.Errorf("%s: no phases defined", path)
+	}
+
+	warmup, err := parseDur(file.Warmup)
+	if err != nil {
+		return nil, fmt.Errorf("warmup %q: %w", file.Warmup, err)
+	}
+
+	phases := make([]resolvedPhase, 0, len(file.Phases))
+	for i, spec := range file.Phases {
+		dur, err := parseDur(spec.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("phase %d duration %q: %w", i+1, spec.Duration, err)
+		}
+		phases = append(phases, resolvedPhase{Duration: dur, ArrivalRate: spec.ArrivalRate, RampTo: spec.RampTo})
+	}
+
+	for _, flow := range file.Flows {
+		if len(flow.Steps) == 0 {
+			return nil, fmt.Errorf("flow %q has no steps", flow.Name)
+		}
+		for _, step := range flow.Steps {
+			switch step.Type {
+			case "auth", "auth_reject", "acct_start", "acct_interim", "acct_stop":
+			default:
+				return nil, fmt.Errorf("flow %q: unknown step type %q", flow.Name, step.Type)
+			}
+		}
+	}
+
+	s := &Scenario{Warmup: warmup, Phases: phases, Flows: file.Flows}
+	for _, flow := range file.Flows {
+		s.totalW += flow.Weight
+		s.cumWeights = append(s.cumWeights, s.totalW)
+	}
+	if s.totalW <= 0 {
+		return nil, fmt.Errorf("%s: flow weights must sum to more than zero", path)
+	}
+	return s, nil
+}
+
+// parseDur parses s with time.ParseDuration, treating "" as 0 rather than an
+// error so a field a caller doesn't need can be omitted.
+func parseDur(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// pickFlow chooses a flow at random, weighted by FlowSpec.Weight.
+func (s *Scenario) pickFlow(rng RNG) FlowSpec {
+	target := rng.Float64() * s.totalW
+	for i, cum := range s.cumWeights {
+		if target < cum {
+			return s.Flows[i]
+		}
+	}
+	return s.Flows[len(s.Flows)-1]
+}
+
+// ScenarioRunner drives a Scenario's open-model arrival schedule, launching
+// one flow execution per arrival without waiting for earlier ones to
+// complete. maxInFlight bounds the number of concurrently running flows so a
+// server that can't keep up sheds arrivals instead of piling up goroutines
+// without limit.
+type ScenarioRunner struct {
+	Config      *Config
+	Users       []RadiusUser
+	Rng         RNG
+	AuthSink    *sliceSink
+	AcctReport  *Reporter
+	MaxInFlight int
+
+	inFlight chan struct{}
+	dropped  int64
+}
+
+// Run executes the scenario's warmup (a constant-rate phase at the first
+// phase's starting rate, whose results are discarded) followed by every
+// configured phase in order, blocking until the last one completes.
+func (r *ScenarioRunner) Run(ctx context.Context, s *Scenario) {
+	maxInFlight := r.MaxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = 2000
+	}
+	r.inFlight = make(chan struct{}, maxInFlight)
+
+	if s.Warmup > 0 && len(s.Phases) > 0 {
+		log.Printf("scenario: warmup for %s at %.0f/sec (results discarded)", s.Warmup, s.Phases[0].ArrivalRate)
+		r.runPhase(ctx, s, resolvedPhase{Duration: s.Warmup, ArrivalRate: s.Phases[0].ArrivalRate}, true)
+	}
+
+	for i, phase := range s.Phases {
+		log.Printf("scenario: phase %d/%d for %s, %.0f -> %.0f/sec", i+1, len(s.Phases), phase.Duration, phase.ArrivalRate, rampEnd(phase))
+		r.runPhase(ctx, s, phase, false)
+	}
+
+	if r.dropped > 0 {
+		log.Printf("scenario: dropped %d arrivals that exceeded -max-inflight=%d", r.dropped, maxInFlight)
+	}
+}
+
+func rampEnd(p resolvedPhase) float64 {
+	if p.RampTo > 0 {
+		return p.RampTo
+	}
+	return p.ArrivalRate
+}
+
+// runPhase schedules arrivals for the duration of phase at its (possibly
+// ramping) rate. warmup flows still execute real requests against the
+// server, just with their results thrown away, since the point of a
+// warm-up is to prime server-side caches/connections, not to skip load.
+func (r *ScenarioRunner) runPhase(ctx context.Context, s *Scenario, phase resolvedPhase, warmup bool) {
+	if phase.Duration <= 0 {
+		return
+	}
+	start := time.Now()
+	deadline := start.Add(phase.Duration)
+
+	for {
+		now := time.Now()
+		if now.After(deadline) || ctx.Err() != nil {
+			return
+		}
+
+		rate := instantRate(phase, now.Sub(start))
+		if rate <= 0 {
+			rate = 1 // avoid a zero/negative interval spinning the loop
+		}
+		interval := time.Duration(float64(time.Second) / rate)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		flow := s.pickFlow(r.Rng)
+		select {
+		case r.inFlight <- struct{}{}:
+			go func() {
+				defer func() { <-r.inFlight }()
+				r.runFlow(ctx, flow, warmup)
+			}()
+		default:
+			r.dropped++
+		}
+	}
+}
+
+// instantRate linearly interpolates phase.ArrivalRate toward phase.RampTo
+// (or holds it constant when RampTo is 0) over elapsed time into the phase.
+func instantRate(phase resolvedPhase, elapsed time.Duration) float64 {
+	if phase.RampTo <= 0 || phase.Duration <= 0 {
+		return phase.ArrivalRate
+	}
+	frac := float64(elapsed) / float64(phase.Duration)
+	if frac > 1 {
+		frac = 1
+	}
+	return phase.ArrivalRate + frac*(phase.RampTo-phase.ArrivalRate)
+}
+
+// runFlow picks a random user and executes flow's steps against it in
+// order, stopping early if an auth or acct_start step fails or an accounting
+// step is attempted before a session exists. A failed acct_interim,
+// acct_stop or auth_reject step does not stop the flow.
+func (r *ScenarioRunner) runFlow(ctx context.Context, flow FlowSpec, warmup bool) {
+	user := r.Users[r.Rng.Intn(len(r.Users))]
+	nasPort := 30000 + r.Rng.Intn(1_000_000)
+
+	var session *AccountingSession
+	for _, step := range flow.Steps {
+		if ctx.Err() != nil {
+			return
+		}
+		repeat := step.Repeat
+		if repeat < 1 {
+			repeat = 1
+		}
+		for n := 0; n < repeat; n++ {
+			r.think(step)
+			switch step.Type {
+			case "auth":
+				result, err := TestAuthenticationWithAttrs(r.Config, user.Username, user.Password, stepAttrs(step))
+				r.recordAuth(result, warmup)
+				if err != nil || result == nil || !result.Success {
+					return
+				}
+			case "auth_reject":
+				result, err := TestAuthenticationWithAttrs(r.Config, user.Username, user.Username+"-wrong", stepAttrs(step))
+				if err != nil {
+					log.Printf("scenario: auth_reject probe for %s: %v", user.Username, err)
+				}
+				r.recordAuth(result, warmup)
+			case "acct_start":
+				session = newScenarioSession(user.Username, r.Rng)
+				sent := time.Now()
+				err := SendAccountingStartWithAttrs(r.Config, session, nasPort, stepAttrs(step))
+				r.recordAcct(PacketStart, err, time.Since(sent), warmup)
+				if err != nil {
+					return
+				}
+				activeSessions.Register(session)
+			case "acct_interim":
+				if session == nil {
+					return
+				}
+				advanceSessionTraffic(session, r.Rng)
+				elapsed := int(time.Since(session.StartTime).Seconds())
+				sent := time.Now()
+				err := SendAccountingInterimUpdate(r.Config, session, nasPort, r.Rng, elapsed)
+				r.recordAcct(PacketInterim, err, time.Since(sent), warmup)
+			case "acct_stop":
+				if session == nil {
+					return
+				}
+				cause := step.TerminateCause
+				if cause == "" {
+					cause = "User-Request"
+				}
+				session.Duration = int(time.Since(session.StartTime).Seconds())
+				sent := time.Now()
+				err := SendAccountingStopWithCause(r.Config, session, nasPort, r.Rng, cause)
+				r.recordAcct(PacketStop, err, time.Since(sent), warmup)
+				activeSessions.Remove(session.UniqueID)
+				session = nil
+			}
+		}
+	}
+}
+
+// think sleeps a uniform random duration in [ThinkMin, ThinkMax] between
+// step attempts, if the step configures one.
+func (r *ScenarioRunner) think(step StepSpec) {
+	min, err := parseDur(step.ThinkMin)
+	if err != nil || min <= 0 {
+		return
+	}
+	max, err := parseDur(step.ThinkMax)
+	if err != nil || max <= min {
+		time.Sleep(min)
+		return
+	}
+	time.Sleep(min + time.Duration(r.Rng.Float64()*float64(max-min)))
+}
+
+// stepAttrs builds the Called-Station-Id/NAS-Port-Type overrides a step
+// configures, or nil when it configures neither.
+func stepAttrs(step StepSpec) []Attribute {
+	if step.CalledStationID == "" && step.NASPortType == "" {
+		return nil
+	}
+	p := &Packet{}
+	if step.CalledStationID != "" {
+		p.AttrString(AttrCalledStationID, step.CalledStationID)
+	}
+	if step.NASPortType != "" {
+		p.AttrString(AttrNASPortType, step.NASPortType)
+	}
+	return p.Attributes
+}
+
+// newScenarioSession builds a fresh AccountingSession for an acct_start step.
+func newScenarioSession(username string, rng RNG) *AccountingSession {
+	now := time.Now()
+	return &AccountingSession{
+		Username:        username,
+		SessionID:       fmt.Sprintf("scenario-session-%d", now.UnixNano()),
+		UniqueID:        gofakeit.UUID(),
+		FramedIPAddress: GenerateRandomIP(rng),
+		StartTime:       now,
+		LastUpdate:      now,
+		Profile:         pickTrafficProfile(rng),
+	}
+}
+
+func (r *ScenarioRunner) recordAuth(result *AuthResult, warmup bool) {
+	if warmup || result == nil {
+		return
+	}
+	r.AuthSink.Record(*result)
+}
+
+func (r *ScenarioRunner) recordAcct(kind PacketKind, err error, latency time.Duration, warmup bool) {
+	if warmup || r.AcctReport == nil {
+		return
+	}
+	r.AcctReport.Record(kind, classifyOutcome(err), latency)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// simulatorShardCount is the number of locks shardedSessions spreads its
+// table across; 10k+ concurrent SessionSimulators would otherwise all
+// contend on the one mutex a plain map needs.
+const simulatorShardCount = 64
+
+// shardedSessions is a sharded, map-backed session table keyed by
+// AccountingSession.UniqueID: each key hashes to one of simulatorShardCount
+// independently-locked shards, so Store/Delete from unrelated sessions only
+// block each other when their keys land in the same shard. It exists alongside the single-lock SessionRegistry
+// (activeSessions) rather than replacing it - CoAListener resolution stays
+// on that shared registry, this is purely SessionSimulator's own
+// bookkeeping for reporting how many sessions are currently live.
+type shardedSessions struct {
+	shards [simulatorShardCount]*sessionShard
+}
+
+type sessionShard struct {
+	mu       sync.RWMutex
+	sessions map[string]*AccountingSession
+}
+
+// newShardedSessions creates an empty sharded table.
+func newShardedSessions() *shardedSessions {
+	s := &shardedSessions{}
+	for i := range s.shards {
+		s.shards[i] = &sessionShard{sessions: make(map[string]*AccountingSession)}
+	}
+	return s
+}
+
+// shardFor picks uniqueID's shard with a cheap FNV-1a-style fold; it only
+// needs to spread keys evenly, not resist collisions.
+func (s *shardedSessions) shardFor(uniqueID string) *sessionShard {
+	var h uint32 = 2166136261
+	for i := 0; i < len(uniqueID); i++ {
+		h = (h ^ uint32(uniqueID[i])) * 16777619
+	}
+	return s.shards[h%simulatorShardCount]
+}
+
+// Store adds or replaces session, keyed by its UniqueID.
+func (s *shardedSessions) Store(session *AccountingSession) {
+	shard := s.shardFor(session.UniqueID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.sessions[session.UniqueID] = session
+}
+
+// Delete removes uniqueID, if present.
+func (s *shardedSessions) Delete(uniqueID string) {
+	shard := s.shardFor(uniqueID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	delete(shard.sessions, uniqueID)
+}
+
+// Len returns the number of sessions currently tracked, summed across shards.
+func (s *shardedSessions) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		n += len(shard.sessions)
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// SessionSimulator drives one virtual user through its whole lifecycle:
+// Access-Request, Accounting-Start, an Accounting-Interim-Update every
+// InterimInterval with octet counters drawn from that session's
+// TrafficModel, then Accounting-Stop. It stops early with Acct-Terminate-
+// Cause "NAS-Request" if ctx is canceled, or TerminateCause (default
+// "Session-Timeout") once Duration has elapsed.
+type SessionSimulator struct {
+	Config          *Config
+	Reporter        *Reporter
+	Sessions        *shardedSessions
+	InterimInterval time.Duration
+	Duration        time.Duration // 0 runs until ctx is canceled
+	TerminateCause  string
+}
+
+// Run authenticates user, then accounts for its session until Duration
+// elapses or ctx is canceled, reporting every Start/Interim/Stop packet to
+// s.Reporter. nasPort and rng are per-call so callers can run many
+// SessionSimulator.Run goroutines concurrently over one shared Config.
+func (s *SessionSimulator) Run(ctx context.Context, user RadiusUser, nasPort int, rng RNG, model TrafficModel) error {
+	authResult, err := TestAuthentication(s.Config, user.Username, user.Password)
+	if err != nil {
+		return fmt.Errorf("access-request for %s: %w", user.Username, err)
+	}
+	if !authResult.Success {
+		return fmt.Errorf("access-request for %s was rejected", user.Username)
+	}
+
+	now := time.Now()
+	session := &AccountingSession{
+		Username:        user.Username,
+		SessionID:       fmt.Sprintf("sim-session-%d-%d", now.UnixNano(), nasPort),
+		UniqueID:        fmt.Sprintf("sim-%d-%d", now.UnixNano(), nasPort),
+		FramedIPAddress: GenerateRandomIP(rng),
+		StartTime:       now,
+		LastUpdate:      now,
+		Profile:         pickTrafficProfile(rng),
+		Model:           model,
+	}
+	s.Sessions.Store(session)
+	defer s.Sessions.Delete(session.UniqueID)
+
+	sendStart := time.Now()
+	err = SendAccountingStart(s.Config, session, nasPort)
+	s.Reporter.Record(PacketStart, classifyOutcome(err), time.Since(sendStart))
+	if err != nil {
+		return fmt.Errorf("accounting-start for %s: %w", user.Username, err)
+	}
+
+	var deadline time.Time
+	if s.Duration > 0 {
+		deadline = now.Add(s.Duration)
+	}
+	cause := s.TerminateCause
+	if cause == "" {
+		cause = "Session-Timeout"
+	}
+
+	timer := GetTimer(s.InterimInterval)
+	defer PutTimer(timer)
+
+	sessionTime := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			cause = "NAS-Request"
+			break loop
+		case <-timer.C:
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				break loop
+			}
+			timer.Reset(s.InterimInterval)
+			sessionTime += int(s.InterimInterval.Seconds())
+
+			sendInterim := time.Now()
+			err := SendAccountingInterimUpdate(s.Config, session, nasPort, rng, sessionTime)
+			s.Reporter.Record(PacketInterim, classifyOutcome(err), time.Since(sendInterim))
+			if err != nil {
+				log.Printf("  ✗ Interim-Update failed for %s: %v", user.Username, err)
+			}
+		}
+	}
+
+	session.Duration = sessionTime
+	sendStop := time.Now()
+	err = SendAccountingStopWithCause(s.Config, session, nasPort, rng, cause)
+	s.Reporter.Record(PacketStop, classifyOutcome(err), time.Since(sendStop))
+	if err != nil {
+		return fmt.Errorf("accounting-stop for %s: %w", user.Username, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import "sync"
+
+// SessionRegistry is a thread-safe table of in-flight accounting sessions,
+// keyed by UniqueID, so subsystems other than the one that created a
+// session (e.g. CoAListener) can look it up and act on it.
+type SessionRegistry struct {
+	mu       sync.RWMutex
+	sessions map[string]*AccountingSession
+}
+
+// NewSessionRegistry creates an empty registry.
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sessions: make(map[string]*AccountingSession)}
+}
+
+// activeSessions is the process-wide registry populated by CreateAccountingSession.
+var activeSessions = NewSessionRegistry()
+
+// Register adds or replaces a session, keyed by its UniqueID.
+func (r *SessionRegistry) Register(session *AccountingSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.UniqueID] = session
+	Metrics.ActiveSessions.Inc()
+}
+
+// Remove deletes a session from the registry, if present.
+func (r *SessionRegistry) Remove(uniqueID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[uniqueID]; ok {
+		delete(r.sessions, uniqueID)
+		Metrics.ActiveSessions.Dec()
+	}
+}
+
+// Find returns the first tracked session matching acctSessionID, username,
+// or framedIP. RFC 5176 §3 lets a CoA/Disconnect-Request identify its
+// target session by any combination of these; empty criteria are ignored.
+func (r *SessionRegistry) Find(acctSessionID, username, framedIP string) (*AccountingSession, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.sessions {
+		if acctSessionID != "" && s.SessionID != acctSessionID {
+			continue
+		}
+		if username != "" && s.Username != username {
+			continue
+		}
+		if framedIP != "" && s.FramedIPAddress != framedIP {
+			continue
+		}
+		return s, true
+	}
+	return nil, false
+}
+
+// All returns a snapshot of every tracked session.
+func (r *SessionRegistry) All() []*AccountingSession {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*AccountingSession, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		out = append(out, s)
+	}
+	return out
+}
@@ -5,36 +5,60 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 func main() {
+	// Load configuration (env-sourced defaults for the rate-limit flags below)
+	config := LoadConfig()
+
 	// Command line flags
 	onlineUsers := flag.Int("online", 20, "Number of concurrent online users to simulate")
 	updateInterval := flag.Int("interval", 60, "Interim-update interval in seconds")
 	maxUsers := flag.Int("max-users", 100, "Maximum number of users to fetch from database")
+	seed := flag.Int64("seed", 0, "RNG seed for reproducible traffic (default: random)")
+	globalPPS := flag.Int("global-pps", config.GlobalPPS, "Global accounting rate limit, requests/sec (0=unlimited)")
+	globalBurst := flag.Int("global-burst", config.GlobalBurst, "Global accounting rate limit burst size")
+	perUserPPS := flag.Int("per-user-pps", config.PerUserPPS, "Per-user accounting rate limit, requests/sec (0=unlimited)")
+	perUserBurst := flag.Int("per-user-burst", config.PerUserBurst, "Per-user accounting rate limit burst size")
+	reportJSON := flag.String("report-json", "", "Periodically write a JSON summary snapshot to this path (disabled if empty)")
+	reportInterval := flag.Duration("report-interval", 10*time.Second, "How often to write -report-json snapshots")
+	trafficModel := flag.String("traffic-model", "constant", "Traffic shape for simulated byte counters: constant, diurnal, bursty, or replay")
+	trafficReplayFile := flag.String("traffic-replay-file", "", "CSV file of (timestamp, bytes_in, bytes_out) samples; required when -traffic-model=replay")
 	flag.Parse()
 
+	rng := NewSyncRand(NewSeededRand(*seed))
+
 	log.Println("========================================")
 	log.Println("Simulate Online Users")
 	log.Println("========================================")
 
-	// Verify radclient is installed
-	if err := VerifyRadclientInstalled(); err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-
-	// Load configuration
-	config := LoadConfig()
 	log.Printf("RADIUS Server: %s:%s", config.RadiusServer, config.RadiusAcctPort)
 	log.Printf("Simulating %d concurrent users", *onlineUsers)
 	log.Printf("Interim-update every %d seconds", *updateInterval)
 
+	globalLimiter := newRateLimiter(*globalPPS, *globalBurst)
+	if *globalPPS > 0 {
+		log.Printf("Global accounting rate limit: %d/s (burst %d)", *globalPPS, *globalBurst)
+	}
+
+	reporter := NewReporter()
+
+	coaListener := NewCoAListener(config, activeSessions, rng)
+	go func() {
+		if err := coaListener.ListenAndServe(); err != nil {
+			log.Printf("CoA listener stopped: %v", err)
+		}
+	}()
+	defer coaListener.Close()
+	log.Println("CoA/Disconnect listener: send CoA-Request/Disconnect-Request via SendCoARequest/SendDisconnectRequest to act on an online session by username or Acct-Session-Id")
+
 	// Connect to database
 	db, err := ConnectDB(config)
 	if err != nil {
@@ -70,15 +94,25 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	if *reportJSON != "" {
+		log.Printf("Writing report snapshots to %s every %s", *reportJSON, *reportInterval)
+		go reporter.RunPeriodicJSON(ctx, *reportJSON, *reportInterval)
+	}
+
 	var wg sync.WaitGroup
 
 	// Start sessions for each user
 	for i, user := range users {
+		model, err := NewTrafficModel(*trafficModel, i, *trafficReplayFile)
+		if err != nil {
+			log.Fatalf("Traffic model: %v", err)
+		}
+
 		wg.Add(1)
-		go func(userNum int, u RadiusUser) {
+		go func(userNum int, u RadiusUser, m TrafficModel) {
 			defer wg.Done()
-			keepUserOnline(ctx, config, u, userNum, *updateInterval)
-		}(i, user)
+			keepUserOnline(ctx, config, u, userNum, *updateInterval, rng, globalLimiter, *perUserPPS, *perUserBurst, reporter, coaListener, m)
+		}(i, user, model)
 
 		// Stagger session starts
 		time.Sleep(500 * time.Millisecond)
@@ -92,58 +126,121 @@ func main() {
 	// Wait for all goroutines to finish
 	wg.Wait()
 
+	reporter.Close()
+	reporter.PrintSummary()
+
 	log.Println("\n========================================")
 	log.Println("All sessions stopped gracefully")
 	log.Println("========================================")
 }
 
-func keepUserOnline(ctx context.Context, config *Config, user RadiusUser, userNum, updateInterval int) {
+// keepUserOnline holds user's session open indefinitely, pacing its
+// Start and Interim-Update traffic through both globalLimiter (shared across
+// every online user) and a per-session limiter sized from
+// perUserPPS/perUserBurst, so a large -online count can't overwhelm the
+// RADIUS server or its database regardless of how many goroutines are
+// running. The Acct-Stop, on shutdown or on a Disconnect, is sent without
+// waiting on either limiter.
+func keepUserOnline(ctx context.Context, config *Config, user RadiusUser, userNum, updateInterval int, rng RNG, globalLimiter *rate.Limiter, perUserPPS, perUserBurst int, reporter *Reporter, coaListener *CoAListener, trafficModel TrafficModel) {
+	userLimiter := newRateLimiter(perUserPPS, perUserBurst)
+
+	now := time.Now()
 	session := &AccountingSession{
 		Username:        user.Username,
-		SessionID:       fmt.Sprintf("persistent-%d-%d", time.Now().Unix(), userNum),
-		UniqueID:        fmt.Sprintf("%d", rand.Int63()),
+		SessionID:       fmt.Sprintf("persistent-%d-%d", now.Unix(), userNum),
+		UniqueID:        fmt.Sprintf("%d", rng.Int63()),
 		FramedIPAddress: fmt.Sprintf("10.30.%d.%d", userNum/256, userNum%256),
-		StartTime:       time.Now(),
-		BytesIn:         0,
-		BytesOut:        0,
+		StartTime:       now,
+		LastUpdate:      now,
+		Profile:         pickTrafficProfile(rng),
+		Model:           trafficModel,
 	}
 
+	activeSessions.Register(session)
+	defer activeSessions.Remove(session.UniqueID)
+
+	actions, unwatch := coaListener.Watch(session)
+	defer unwatch()
+
 	nasPort := 30000 + userNum
 
 	// Send Start
 	log.Printf("User %s: Starting session (IP: %s)", user.Username, session.FramedIPAddress)
-	if err := SendAccountingStart(config, session, nasPort); err != nil {
+	if err := waitLimiters(ctx, globalLimiter, userLimiter); err != nil {
+		return
+	}
+	sendStart := time.Now()
+	err := SendAccountingStart(config, session, nasPort)
+	reporter.Record(PacketStart, classifyOutcome(err), time.Since(sendStart))
+	if err != nil {
 		log.Printf("  ✗ Failed to send Acct-Start: %v", err)
 		return
 	}
 	log.Printf("  ✓ User %s is now ONLINE", user.Username)
 
-	// Send Interim-Updates periodically
-	ticker := time.NewTicker(time.Duration(updateInterval) * time.Second)
-	defer ticker.Stop()
+	// Send Interim-Updates periodically, via a pooled timer rather than a
+	// per-session time.Ticker so thousands of concurrent sessions don't each
+	// pin their own entry in the runtime's timer heap.
+	interimPeriod := time.Duration(updateInterval) * time.Second
+	timer := GetTimer(interimPeriod)
+	defer PutTimer(timer)
 
 	sessionTime := 0
 	for {
 		select {
 		case <-ctx.Done():
-			// Send Stop on shutdown
+			// Send Stop on shutdown. Unrated: ctx is already canceled here, and
+			// a final Stop must get through regardless of burst capacity.
 			log.Printf("User %s: Stopping session...", user.Username)
 			session.Duration = sessionTime
-			if err := SendAccountingStop(config, session, nasPort); err != nil {
+			sendStop := time.Now()
+			err := SendAccountingStop(config, session, nasPort, rng)
+			reporter.Record(PacketStop, classifyOutcome(err), time.Since(sendStop))
+			if err != nil {
 				log.Printf("  ✗ Failed to send Acct-Stop: %v", err)
 			} else {
 				log.Printf("  ✓ User %s session stopped", user.Username)
 			}
 			return
 
-		case <-ticker.C:
-			sessionTime += updateInterval
+		case action := <-actions:
+			if action.disconnect {
+				// An operator honored a Disconnect-Request against this
+				// session. Interims are only sent from this loop, which
+				// returns right after the Admin-Reset Stop below, so none
+				// follows the Stop; the deferred PutTimer then stops the
+				// timer.
+				log.Printf("User %s: Disconnected by CoA", user.Username)
+				session.Duration = sessionTime
+				sendStop := time.Now()
+				err := SendAccountingStopWithCause(config, session, nasPort, rng, "Admin-Reset")
+				reporter.Record(PacketStop, classifyOutcome(err), time.Since(sendStop))
+				if err != nil {
+					log.Printf("  ✗ Failed to send Admin-Reset Acct-Stop: %v", err)
+				} else {
+					log.Printf("  ✓ User %s session disconnected", user.Username)
+				}
+				return
+			}
 
-			// Simulate data transfer (random realistic values)
-			session.BytesIn += int64(rand.Intn(50000000))  // Up to 50MB per interval
-			session.BytesOut += int64(rand.Intn(10000000)) // Up to 10MB per interval
+			applyCoAAttributes(session, action.attrs)
+			log.Printf("User %s: CoA applied (IP: %s)", user.Username, session.FramedIPAddress)
+
+		case <-timer.C:
+			timer.Reset(interimPeriod)
+			sessionTime += updateInterval
 
-			if err := SendAccountingInterimUpdate(config, session, nasPort, sessionTime); err != nil {
+			// On ctx error here, loop back around rather than returning: the
+			// ctx.Done() case above is the one responsible for sending the
+			// final Acct-Stop, and it may not have been the case select chose
+			// this iteration even though ctx is already canceled.
+			if err := waitLimiters(ctx, globalLimiter, userLimiter); err != nil {
+				continue
+			}
+			sendInterim := time.Now()
+			err := SendAccountingInterimUpdate(config, session, nasPort, rng, sessionTime)
+			reporter.Record(PacketInterim, classifyOutcome(err), time.Since(sendInterim))
+			if err != nil {
 				log.Printf("  ⚠ User %s: Failed to send Interim-Update: %v", user.Username, err)
 			} else {
 				log.Printf("  ↻ User %s: Interim-Update sent (Time: %s, Down: %s, Up: %s)",
@@ -156,3 +253,25 @@ func keepUserOnline(ctx context.Context, config *Config, user RadiusUser, userNu
 		}
 	}
 }
+
+// newRateLimiter builds a limiter from pps/burst; pps<=0 means unlimited.
+func newRateLimiter(pps, burst int) *rate.Limiter {
+	if pps <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(pps), burst)
+}
+
+// waitLimiters blocks on every limiter in turn, returning as soon as one
+// reports ctx canceled.
+func waitLimiters(ctx context.Context, limiters ...*rate.Limiter) error {
+	for _, l := range limiters {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
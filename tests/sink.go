@@ -0,0 +1,62 @@
+package main
+
+import "time"
+
+// MetricsSink receives a live per-attempt feed of auth/accounting outcomes,
+// on top of whatever Transport already reports at the wire level into
+// Metrics. TestAuthenticationWithAttrs and sendAcct report to every sink in
+// metricsSinks after each attempt, success or not.
+type MetricsSink interface {
+	AuthResult(result string, rtt time.Duration)
+	AcctResult(kind string, rtt time.Duration)
+	Close() error
+}
+
+// metricsSinks are the active MetricsSink backends for this process.
+// Prometheus is always on, since Metrics is itself a process-wide registry
+// regardless of whether ServeMetrics is serving /metrics; EnableStatsD
+// appends a second backend when a run asks for one.
+var metricsSinks = []MetricsSink{promSink{}}
+
+// promSink satisfies MetricsSink by incrementing the package-wide
+// Metrics.AuthTotal/AcctTotal counters already exposed by ServeMetrics.
+type promSink struct{}
+
+func (promSink) AuthResult(result string, _ time.Duration) {
+	Metrics.AuthTotal.WithLabelValues(result).Inc()
+}
+
+func (promSink) AcctResult(kind string, _ time.Duration) {
+	Metrics.AcctTotal.WithLabelValues(kind).Inc()
+}
+
+func (promSink) Close() error { return nil }
+
+// EnableStatsD dials addr and adds a StatsD-backed MetricsSink to
+// metricsSinks, mirroring every auth/accounting outcome to it as well as to
+// Prometheus.
+func EnableStatsD(addr string) error {
+	sink, err := NewStatsDSink(addr)
+	if err != nil {
+		return err
+	}
+	metricsSinks = append(metricsSinks, sink)
+	return nil
+}
+
+// recordAuthResult reports an Access-Request outcome ("accept", "reject",
+// or "timeout") to every active sink.
+func recordAuthResult(result string, rtt time.Duration) {
+	for _, sink := range metricsSinks {
+		sink.AuthResult(result, rtt)
+	}
+}
+
+// recordAcctResult reports an Accounting-Request attempt ("start",
+// "interim", or "stop") to every active sink, regardless of whether it
+// succeeded.
+func recordAcctResult(kind string, rtt time.Duration) {
+	for _, sink := range metricsSinks {
+		sink.AcctResult(kind, rtt)
+	}
+}
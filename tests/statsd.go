@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// statsDSink is a MetricsSink backed by a StatsD/dogstatsd-style UDP client:
+// fire-and-forget writes with no reply expected, so a dropped packet costs
+// one graph point rather than slowing down the load test.
+type statsDSink struct {
+	conn net.Conn
+}
+
+// NewStatsDSink dials addr (host:port, UDP) and returns a MetricsSink that
+// mirrors every auth/accounting outcome to it as StatsD counters and timers.
+func NewStatsDSink(addr string) (MetricsSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial statsd %s: %w", addr, err)
+	}
+	return &statsDSink{conn: conn}, nil
+}
+
+func (s *statsDSink) AuthResult(result string, rtt time.Duration) {
+	s.send(fmt.Sprintf("radius.auth.%s:1|c\n", result))
+	s.send(fmt.Sprintf("radius.auth.rtt:%d|ms\n", rtt.Milliseconds()))
+}
+
+func (s *statsDSink) AcctResult(kind string, rtt time.Duration) {
+	s.send(fmt.Sprintf("radius.acct.%s:1|c\n", kind))
+	s.send(fmt.Sprintf("radius.acct.rtt:%d|ms\n", rtt.Milliseconds()))
+}
+
+func (s *statsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// send best-effort writes line to the StatsD UDP socket. A dropped metric
+// isn't worth failing, retrying, or even logging a load test over.
+func (s *statsDSink) send(line string) {
+	s.conn.Write([]byte(line))
+}
@@ -10,16 +10,14 @@ import (
 func main() {
 	// Command line flags
 	maxUsers := flag.Int("users", 20, "Maximum number of users to create sessions for")
+	seed := flag.Int64("seed", 0, "RNG seed for reproducible traffic (default: random)")
 	flag.Parse()
 
 	log.Println("========================================")
 	log.Println("RADIUS Accounting Test")
 	log.Println("========================================")
 
-	// Verify radclient is installed
-	if err := VerifyRadclientInstalled(); err != nil {
-		log.Fatalf("Error: %v", err)
-	}
+	rng := NewSeededRand(*seed)
 
 	// Load configuration
 	config := LoadConfig()
@@ -55,7 +53,7 @@ func main() {
 	for i, user := range users {
 		fmt.Printf("\n[%d/%d] Creating session for: %s\n", i+1, len(users), user.Username)
 
-		session, err := CreateAccountingSession(config, user.Username, 10000+i)
+		session, err := CreateAccountingSession(config, user.Username, 10000+i, rng)
 		if err != nil {
 			log.Printf("  ✗ FAILED: %v", err)
 			failCount++
@@ -14,20 +14,20 @@ func main() {
 	maxUsers := flag.Int("users", 50, "Maximum number of users to test")
 	verbose := flag.Bool("verbose", false, "Verbose output")
 	concurrent := flag.Int("concurrent", 1, "Number of concurrent authentication requests (1=sequential, 10=10 parallel)")
+	method := flag.String("method", "", "Authentication method: PAP, CHAP, MSCHAPv2, EAP-MD5, EAP-MSCHAPv2 (default: config AUTH_METHOD or PAP)")
 	flag.Parse()
 
 	log.Println("========================================")
 	log.Println("RADIUS Authentication Test")
 	log.Println("========================================")
 
-	// Verify radclient is installed
-	if err := VerifyRadclientInstalled(); err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-
 	// Load configuration
 	config := LoadConfig()
+	if *method != "" {
+		config.AuthMethod = AuthMethod(*method)
+	}
 	log.Printf("RADIUS Server: %s:%s", config.RadiusServer, config.RadiusAuthPort)
+	log.Printf("Auth Method: %s", config.AuthMethod)
 	log.Printf("Database: %s@%s:%s/%s", config.DBUser, config.DBHost, config.DBPort, config.DBName)
 	if *concurrent > 1 {
 		log.Printf("Concurrency: %d parallel authentications", *concurrent)
@@ -74,7 +74,7 @@ func testSequential(config *Config, users []RadiusUser, verbose bool) {
 	for i, user := range users {
 		fmt.Printf("\n[%d/%d] Testing user: %s\n", i+1, len(users), user.Username)
 
-		result, err := TestAuthentication(config, user.Username, user.Password)
+		result, err := TestAuthenticationMethod(config, user.Username, user.Password, config.AuthMethod)
 		if err != nil && !result.Success {
 			log.Printf("  ✗ FAILED: %v", err)
 		} else if result.Success {
@@ -82,7 +82,7 @@ func testSequential(config *Config, users []RadiusUser, verbose bool) {
 
 			// Print returned attributes if verbose
 			if verbose {
-				fmt.Printf("  Response:\n%s\n", result.Message)
+				fmt.Printf("  Response: code=%d attrs=%d rtt=%s\n", result.Code, len(result.Attributes), result.RTT)
 			}
 		} else {
 			log.Printf("  ✗ FAILED - Authentication rejected")
@@ -114,7 +114,7 @@ func testConcurrent(config *Config, users []RadiusUser, concurrency int, verbose
 			defer wg.Done()
 			defer func() { <-semaphore }() // Release semaphore
 
-			result, err := TestAuthentication(config, u.Username, u.Password)
+			result, err := TestAuthenticationMethod(config, u.Username, u.Password, config.AuthMethod)
 
 			if result.Success {
 				atomic.AddInt64(&successCount, 1)
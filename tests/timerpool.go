@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// timerPool pools *time.Timer values so that keepUserOnline (and anything
+// else cycling through a per-session timer instead of a long-lived ticker)
+// doesn't grow the runtime's timer heap by one entry per session at high
+// concurrency.
+var timerPool = sync.Pool{
+	New: func() any {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t
+	},
+}
+
+// GetTimer returns a timer firing after d, reusing a pooled, already-stopped
+// timer when one is available instead of allocating a fresh one. Pair every
+// call with PutTimer once the timer is no longer needed.
+func GetTimer(d time.Duration) *time.Timer {
+	t := timerPool.Get().(*time.Timer)
+	t.Reset(d)
+	return t
+}
+
+// PutTimer stops t and drains any value already sitting on t.C before
+// returning it to the pool, so the next GetTimer caller never observes a
+// stale fire left over from a previous use. Safe to call whether or not t
+// has already fired.
+func PutTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	timerPool.Put(t)
+}
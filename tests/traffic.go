@@ -0,0 +1,49 @@
+package main
+
+// TrafficProfile characterizes a session's bandwidth as a Gaussian bitrate
+// with a fixed download/upload split and mean packet size, so Interim-Update
+// and Stop counters can progress plausibly from one report to the next
+// instead of being resampled from nothing each time.
+type TrafficProfile struct {
+	Name             string
+	MeanBitsPerSec   float64
+	StdDevBitsPerSec float64
+	DownUpRatio      float64 // fraction of bytes that are download (BytesIn)
+	MeanPacketBytes  float64
+}
+
+// trafficProfiles are the characteristic usage shapes a session is drawn from.
+var trafficProfiles = []TrafficProfile{
+	{Name: "voip", MeanBitsPerSec: 87_000, StdDevBitsPerSec: 8_000, DownUpRatio: 0.5, MeanPacketBytes: 200},
+	{Name: "web-browsing", MeanBitsPerSec: 2_000_000, StdDevBitsPerSec: 1_500_000, DownUpRatio: 0.85, MeanPacketBytes: 900},
+	{Name: "video-stream", MeanBitsPerSec: 6_000_000, StdDevBitsPerSec: 2_000_000, DownUpRatio: 0.95, MeanPacketBytes: 1400},
+	{Name: "bulk-transfer", MeanBitsPerSec: 40_000_000, StdDevBitsPerSec: 10_000_000, DownUpRatio: 0.2, MeanPacketBytes: 1500},
+}
+
+// pickTrafficProfile chooses one of the characteristic profiles at random.
+func pickTrafficProfile(rng RNG) TrafficProfile {
+	return trafficProfiles[rng.Intn(len(trafficProfiles))]
+}
+
+// sampleBitsPerSec draws a non-negative bitrate from the profile's Gaussian.
+func (p TrafficProfile) sampleBitsPerSec(rng RNG) float64 {
+	rate := rng.NormFloat64()*p.StdDevBitsPerSec + p.MeanBitsPerSec
+	if rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+// advance samples the traffic generated over elapsed seconds and returns the
+// incremental byte/packet counts to add to a session's running totals.
+func (p TrafficProfile) advance(rng RNG, elapsed float64) (bytesIn, bytesOut, packetsIn, packetsOut int64) {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	totalBytes := int64(p.sampleBitsPerSec(rng) / 8 * elapsed)
+	bytesIn = int64(float64(totalBytes) * p.DownUpRatio)
+	bytesOut = totalBytes - bytesIn
+	packetsIn = int64(float64(bytesIn)/p.MeanPacketBytes) + 1
+	packetsOut = int64(float64(bytesOut)/p.MeanPacketBytes) + 1
+	return
+}
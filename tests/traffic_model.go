@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrafficModel generates per-tick byte counters for a simulated session's
+// Acct-Input-Octets / Acct-Output-Octets progression. Each AccountingSession
+// driven by simulate_online.go gets its own instance, chosen by
+// -traffic-model and seeded from the session's userNum, so a run's traffic
+// shape is reproducible.
+type TrafficModel interface {
+	// Next returns the bytes downloaded/uploaded accrued over interval.
+	Next(interval time.Duration) (in, out int64)
+}
+
+// NewTrafficModel builds the TrafficModel named by kind, drawing its
+// bandwidth parameters from one of trafficProfiles (chosen by userNum) and
+// seeding any internal randomness from userNum for reproducibility.
+// replayFile is only consulted when kind is "replay".
+func NewTrafficModel(kind string, userNum int, replayFile string) (TrafficModel, error) {
+	profile := trafficProfiles[userNum%len(trafficProfiles)]
+
+	switch kind {
+	case "", "constant":
+		return &ConstantRateModel{BitsPerSec: profile.MeanBitsPerSec, DownUpRatio: profile.DownUpRatio}, nil
+	case "diurnal":
+		return &DiurnalModel{
+			PeakBitsPerSec:    profile.MeanBitsPerSec * 2,
+			OffPeakBitsPerSec: profile.MeanBitsPerSec / 4,
+			DownUpRatio:       profile.DownUpRatio,
+		}, nil
+	case "bursty":
+		return &BurstyParetoModel{
+			rng:              rand.New(rand.NewSource(int64(userNum))),
+			DownUpRatio:      profile.DownUpRatio,
+			MeanBurstBytes:   profile.MeanPacketBytes * 200,
+			ParetoAlpha:      1.5,
+			BurstProbability: 0.3,
+		}, nil
+	case "replay":
+		if replayFile == "" {
+			return nil, fmt.Errorf("-traffic-model=replay requires -traffic-replay-file")
+		}
+		return NewReplayModel(replayFile)
+	default:
+		return nil, fmt.Errorf("unknown -traffic-model %q", kind)
+	}
+}
+
+// ConstantRateModel generates traffic at a fixed bitrate, split between
+// download/upload by DownUpRatio.
+type ConstantRateModel struct {
+	BitsPerSec  float64
+	DownUpRatio float64
+}
+
+func (m *ConstantRateModel) Next(interval time.Duration) (in, out int64) {
+	return splitBytes(int64(m.BitsPerSec/8*interval.Seconds()), m.DownUpRatio)
+}
+
+// DiurnalModel models the higher evening usage real ISP traffic shows: a
+// sinusoid with one cycle per 24h, peaking at 20:00 wall-clock local time
+// and troughing at 08:00.
+type DiurnalModel struct {
+	PeakBitsPerSec    float64
+	OffPeakBitsPerSec float64
+	DownUpRatio       float64
+}
+
+func (m *DiurnalModel) Next(interval time.Duration) (in, out int64) {
+	now := time.Now()
+	hourOfDay := float64(now.Hour()) + float64(now.Minute())/60
+	phase := 2 * math.Pi * (hourOfDay - 20) / 24
+
+	mean := (m.PeakBitsPerSec + m.OffPeakBitsPerSec) / 2
+	amplitude := (m.PeakBitsPerSec - m.OffPeakBitsPerSec) / 2
+	bitsPerSec := mean + amplitude*math.Cos(phase)
+
+	return splitBytes(int64(bitsPerSec/8*interval.Seconds()), m.DownUpRatio)
+}
+
+// BurstyParetoModel idles most ticks, then on a burst tick downloads a
+// Pareto-distributed number of bytes, modeling the on/off bursts of
+// real web/streaming traffic far better than a steady rate does.
+type BurstyParetoModel struct {
+	rng              *rand.Rand
+	DownUpRatio      float64
+	MeanBurstBytes   float64 // Pareto scale (x_m)
+	ParetoAlpha      float64 // Pareto shape; lower means heavier tail
+	BurstProbability float64 // chance a given tick contains a burst at all
+}
+
+func (m *BurstyParetoModel) Next(interval time.Duration) (in, out int64) {
+	if m.rng.Float64() > m.BurstProbability {
+		return 0, 0
+	}
+	// Inverse-CDF sampling of a Pareto(x_m, alpha) distribution.
+	u := m.rng.Float64()
+	burst := int64(m.MeanBurstBytes / math.Pow(1-u, 1/m.ParetoAlpha))
+	return splitBytes(burst, m.DownUpRatio)
+}
+
+// splitBytes divides total between download/upload per downUpRatio, the
+// fraction that is download.
+func splitBytes(total int64, downUpRatio float64) (in, out int64) {
+	if total <= 0 {
+		return 0, 0
+	}
+	in = int64(float64(total) * downUpRatio)
+	out = total - in
+	return
+}
+
+// replaySample is one (bytes_in, bytes_out) row of a traffic capture.
+type replaySample struct {
+	In, Out int64
+}
+
+// ReplayModel replays bytes_in/bytes_out deltas read from a CSV capture of
+// (timestamp, bytes_in, bytes_out) rows, cycling back to the start once
+// exhausted. The interval passed to Next is ignored: each call simply
+// advances to the capture's next recorded sample.
+type ReplayModel struct {
+	mu      sync.Mutex
+	samples []replaySample
+	next    int
+}
+
+// NewReplayModel loads samples from a CSV file at path. Rows with fewer than
+// three fields or non-numeric byte counts (e.g. a header) are skipped; a CSV
+// syntax error such as a stray quote fails the load.
+func NewReplayModel(path string) (*ReplayModel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open traffic replay file: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1 // rows may differ in length; short ones are skipped below
+	var samples []replaySample
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read traffic replay file: %w", err)
+		}
+		if len(record) < 3 {
+			continue
+		}
+		in, errIn := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+		out, errOut := strconv.ParseInt(strings.TrimSpace(record[2]), 10, 64)
+		if errIn != nil || errOut != nil {
+			continue // header row or malformed sample
+		}
+		samples = append(samples, replaySample{In: in, Out: out})
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("traffic replay file %s has no usable (timestamp, bytes_in, bytes_out) rows", path)
+	}
+
+	return &ReplayModel{samples: samples}, nil
+}
+
+func (m *ReplayModel) Next(interval time.Duration) (in, out int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.samples[m.next%len(m.samples)]
+	m.next++
+	return s.In, s.Out
+}
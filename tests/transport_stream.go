@@ -0,0 +1,307 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// TransportMode selects how Transport.Exchange callers reach the wire.
+type TransportMode string
+
+const (
+	TransportUDP TransportMode = "udp"
+	TransportTCP TransportMode = "tcp"
+	TransportTLS TransportMode = "tls"
+)
+
+// Exchanger is satisfied by both the UDP Transport and StreamTransport, so
+// call sites built against transport(config) don't need to know which
+// underlying wire protocol is in play.
+type Exchanger interface {
+	Exchange(addr string, req *Packet) (*Packet, time.Duration, error)
+}
+
+// streamTransports caches one StreamTransport per (mode, addr) so repeated
+// transport(config) calls reuse a single long-lived connection instead of
+// reconnecting on every exchange.
+var (
+	streamTransportsMu sync.Mutex
+	streamTransports   = map[string]*StreamTransport{}
+)
+
+// configTransport resolves the cached StreamTransport for addr on first
+// Exchange call and delegates to it from then on.
+type configTransport struct {
+	config *Config
+}
+
+func (c *configTransport) Exchange(addr string, req *Packet) (*Packet, time.Duration, error) {
+	st, err := streamTransportFor(c.config, addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return st.Exchange(addr, req)
+}
+
+// streamTransportFor returns the cached StreamTransport for config's
+// Transport mode and addr, creating and, for TLS, handshake-configuring it
+// on first use.
+func streamTransportFor(config *Config, addr string) (*StreamTransport, error) {
+	key := string(config.Transport) + "|" + addr
+
+	streamTransportsMu.Lock()
+	defer streamTransportsMu.Unlock()
+	if st, ok := streamTransports[key]; ok {
+		return st, nil
+	}
+
+	st := &StreamTransport{
+		Addr:      addr,
+		Network:   "tcp",
+		Secret:    []byte(config.RadiusSecret),
+		Timeout:   config.RadiusTimeout,
+		Retries:   config.RadiusRetries,
+		IdleCheck: 60 * time.Second,
+	}
+	if config.Transport == TransportTLS {
+		tlsConfig, err := buildTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		st.TLSConfig = tlsConfig
+	}
+	streamTransports[key] = st
+	return st, nil
+}
+
+// buildTLSConfig assembles a tls.Config from Config's RadSec options:
+// client certificate, CA bundle, SNI override, and optional SHA-256 cert
+// pinning (RFC 6614 §2.3 notes pinning as an alternative to a full PKI).
+func buildTLSConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: config.TLSServerName}
+
+	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load RadSec client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.TLSCAFile != "" {
+		pem, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read RadSec CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.TLSPinnedSHA256 != "" {
+		pin := config.TLSPinnedSHA256
+		tlsConfig.InsecureSkipVerify = true // verified below via the pin instead
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			for _, raw := range rawCerts {
+				sum := sha256.Sum256(raw)
+				if hex.EncodeToString(sum[:]) == pin {
+					return nil
+				}
+			}
+			return fmt.Errorf("no presented certificate matches pinned SHA-256 %s", pin)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// StreamTransport exchanges RADIUS packets over a long-lived TCP or TLS
+// stream (RFC 6613/6614), framing each packet with its own RADIUS Length
+// header rather than relying on UDP datagram boundaries. It reconnects with
+// exponential backoff on failure and sends an idle Status-Server
+// health-check before reusing a connection that has gone quiet past
+// IdleCheck.
+type StreamTransport struct {
+	Addr      string
+	Network   string // "tcp"; a non-nil TLSConfig layers RadSec on top of it
+	TLSConfig *tls.Config
+	Secret    []byte
+	Timeout   time.Duration
+	Retries   int
+	IdleCheck time.Duration
+
+	mu       sync.Mutex
+	conn     net.Conn
+	lastUsed time.Time
+}
+
+// Exchange sends req over the persistent connection and returns the decoded
+// reply, reconnecting with exponential backoff across retries.
+func (t *StreamTransport) Exchange(addr string, req *Packet) (*Packet, time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wire, err := req.Encode()
+	if err != nil {
+		return nil, 0, fmt.Errorf("encode request: %w", err)
+	}
+
+	retries := t.Retries
+	if retries < 1 {
+		retries = 1
+	}
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	start := time.Now()
+	backoff := 100 * time.Millisecond
+	var lastErr error
+	for attempt := 0; attempt < retries; attempt++ {
+		if attempt > 0 {
+			Metrics.Retransmits.Inc()
+			time.Sleep(backoff)
+			if backoff < 5*time.Second {
+				backoff *= 2
+			}
+		}
+
+		conn, err := t.ensureConn(timeout)
+		if err != nil {
+			lastErr = err
+			t.closeLocked()
+			continue
+		}
+
+		resp, rtt, err := t.roundTrip(conn, wire, req.Code, req.Identifier, timeout, start)
+		if err != nil {
+			lastErr = err
+			t.closeLocked()
+			continue
+		}
+		t.lastUsed = time.Now()
+		return resp, rtt, nil
+	}
+	Metrics.Timeouts.Inc()
+	return nil, time.Since(start), lastErr
+}
+
+func (t *StreamTransport) roundTrip(conn net.Conn, wire []byte, code Code, identifier byte, timeout time.Duration, start time.Time) (*Packet, time.Duration, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(wire); err != nil {
+		return nil, 0, fmt.Errorf("write: %w", err)
+	}
+	codeLabel := fmt.Sprintf("%d", code)
+	Metrics.PacketsSent.WithLabelValues(codeLabel).Inc()
+
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, 0, fmt.Errorf("read length header: %w", err)
+	}
+	length := int(header[2])<<8 | int(header[3])
+	if length < 20 || length > maxPacketSize {
+		return nil, 0, fmt.Errorf("invalid length field: %d", length)
+	}
+
+	body := make([]byte, length)
+	copy(body, header[:])
+	if _, err := io.ReadFull(conn, body[4:]); err != nil {
+		return nil, 0, fmt.Errorf("read packet body: %w", err)
+	}
+	rtt := time.Since(start)
+
+	resp, err := DecodePacket(body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decode response: %w", err)
+	}
+	if resp.Identifier != identifier {
+		return nil, 0, fmt.Errorf("identifier mismatch: sent %d, got %d", identifier, resp.Identifier)
+	}
+
+	Metrics.PacketsReceived.WithLabelValues(fmt.Sprintf("%d", resp.Code)).Inc()
+	Metrics.PacketRTT.WithLabelValues(codeLabel).Observe(rtt.Seconds())
+	return resp, rtt, nil
+}
+
+// ensureConn returns the cached connection - health-checking and replacing
+// it first if it has sat idle past IdleCheck - or dials (and, for RadSec,
+// TLS-handshakes) a fresh one.
+func (t *StreamTransport) ensureConn(timeout time.Duration) (net.Conn, error) {
+	if t.conn != nil && t.IdleCheck > 0 && time.Since(t.lastUsed) > t.IdleCheck {
+		if err := t.healthCheck(timeout); err != nil {
+			t.closeLocked()
+		}
+	}
+	if t.conn != nil {
+		return t.conn, nil
+	}
+
+	conn, err := net.DialTimeout(t.Network, t.Addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", t.Addr, err)
+	}
+	if t.TLSConfig != nil {
+		tlsConn := tls.Client(conn, t.TLSConfig)
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("RadSec TLS handshake with %s: %w", t.Addr, err)
+		}
+		conn = tlsConn
+	}
+
+	t.conn = conn
+	t.lastUsed = time.Now()
+	return conn, nil
+}
+
+// healthCheck sends a Status-Server packet (RFC 5997) over the existing
+// connection to confirm it is still good before an idle connection is reused.
+func (t *StreamTransport) healthCheck(timeout time.Duration) error {
+	req := NewPacket(CodeStatusServer, nextIdentifier(), t.Secret)
+	req.Add(AttrMessageAuthenticator, make([]byte, 16))
+	if err := signMessageAuthenticator(req); err != nil {
+		return fmt.Errorf("sign message-authenticator: %w", err)
+	}
+
+	wire, err := req.Encode()
+	if err != nil {
+		return err
+	}
+
+	t.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := t.conn.Write(wire); err != nil {
+		return err
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(t.conn, header[:]); err != nil {
+		return err
+	}
+	length := int(header[2])<<8 | int(header[3])
+	if length < 20 {
+		return fmt.Errorf("invalid Status-Server reply length %d", length)
+	}
+	_, err = io.ReadFull(t.conn, make([]byte, length-4))
+	return err
+}
+
+// closeLocked closes and forgets the current connection. Caller holds t.mu.
+func (t *StreamTransport) closeLocked() {
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}